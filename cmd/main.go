@@ -1,19 +1,55 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/VikaPaz/time_tracker/internal/app"
+	"github.com/VikaPaz/time_tracker/internal/config"
+	"github.com/VikaPaz/time_tracker/internal/logging"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"os"
 )
 
 // @title Time Tracker API
 // @description This is time_tracker server.
 // @host localhost:8000
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from /auth/login as "Bearer <token>".
 func main() {
-	logger := NewLogger(logrus.DebugLevel, &logrus.TextFormatter{
-		FullTimestamp: true,
-	})
+	configPath := flag.String("config", os.Getenv(config.EnvPrefix+"CONFIG"), "path to a YAML or TOML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
 
-	err := app.Run(logger)
+	conf, err := config.Load(*configPath)
+	if *printConfig {
+		data, marshalErr := yaml.Marshal(conf.Redacted())
+		if marshalErr != nil {
+			fmt.Fprintln(os.Stderr, marshalErr)
+			os.Exit(1)
+		}
+		fmt.Print(string(data))
+	}
+	if err != nil {
+		// Printed as is so every problem ends up on its own line.
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		return
+	}
+
+	// The level and format were validated by config.Load.
+	level, _ := logrus.ParseLevel(conf.Log.Level)
+	var formatter logrus.Formatter = &logrus.JSONFormatter{}
+	if conf.Log.Format == "text" {
+		formatter = &logrus.TextFormatter{FullTimestamp: true}
+	}
+	logger := NewLogger(level, formatter)
+
+	err = app.Run(logger, conf)
 	if err != nil {
 		logger.Fatalln(err)
 	}
@@ -23,5 +59,6 @@ func NewLogger(level logrus.Level, formatter logrus.Formatter) *logrus.Logger {
 	logger := logrus.New()
 	logger.SetLevel(level)
 	logger.SetFormatter(formatter)
+	logger.AddHook(logging.Hook{})
 	return logger
 }
@@ -1,35 +1,67 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	client "github.com/VikaPaz/time_tracker/internal/clients"
+	"github.com/VikaPaz/time_tracker/internal/config"
+	"github.com/VikaPaz/time_tracker/internal/encryption"
+	"github.com/VikaPaz/time_tracker/internal/metrics"
 	"github.com/VikaPaz/time_tracker/internal/models"
 	"github.com/VikaPaz/time_tracker/internal/repository"
+	clientRepo "github.com/VikaPaz/time_tracker/internal/repository/client"
+	healthRepo "github.com/VikaPaz/time_tracker/internal/repository/health"
+	invoiceRepo "github.com/VikaPaz/time_tracker/internal/repository/invoice"
+	organizationRepo "github.com/VikaPaz/time_tracker/internal/repository/organization"
+	"github.com/VikaPaz/time_tracker/internal/repository/project"
+	"github.com/VikaPaz/time_tracker/internal/repository/report"
 	"github.com/VikaPaz/time_tracker/internal/repository/task"
+	"github.com/VikaPaz/time_tracker/internal/repository/team"
 	"github.com/VikaPaz/time_tracker/internal/repository/user"
 	"github.com/VikaPaz/time_tracker/internal/server"
+	authService "github.com/VikaPaz/time_tracker/internal/service/auth"
+	clientService "github.com/VikaPaz/time_tracker/internal/service/client"
+	healthService "github.com/VikaPaz/time_tracker/internal/service/health"
+	invoiceService "github.com/VikaPaz/time_tracker/internal/service/invoice"
+	organizationService "github.com/VikaPaz/time_tracker/internal/service/organization"
+	projectService "github.com/VikaPaz/time_tracker/internal/service/project"
+	reportService "github.com/VikaPaz/time_tracker/internal/service/report"
 	taskService "github.com/VikaPaz/time_tracker/internal/service/task"
+	teamService "github.com/VikaPaz/time_tracker/internal/service/team"
 	userService "github.com/VikaPaz/time_tracker/internal/service/user"
-	"github.com/joho/godotenv"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/google/uuid"
 	"github.com/pressly/goose"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/sirupsen/logrus"
+	"math"
 	"net/http"
-	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 )
 
-func Run(logger *logrus.Logger) error {
-	if err := godotenv.Overload(); err != nil {
-		logger.Errorf("Error loading .env file")
-		return models.ErrLoadEnvFailed
+func Run(logger *logrus.Logger, conf config.Config) error {
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter:    conf.Tracing.Exporter,
+		Endpoint:    conf.Tracing.OTLPEndpoint,
+		Insecure:    conf.Tracing.OTLPInsecure,
+		ServiceName: conf.Tracing.ServiceName,
+	})
+	if err != nil {
+		logger.Errorf("Error setting up tracing")
+		return err
 	}
+	defer flushTraces(logger, shutdownTracing, conf.Server.ShutdownTimeout)
 
 	confPostgres := repository.Config{
-		Host:     os.Getenv("HOST"),
-		Port:     os.Getenv("POSTGRES_PORT"),
-		User:     os.Getenv("USER"),
-		Password: os.Getenv("PASSWORD"),
-		Dbname:   os.Getenv("DB_NAME"),
+		Host:     conf.Postgres.Host,
+		Port:     strconv.Itoa(conf.Postgres.Port),
+		User:     conf.Postgres.User,
+		Password: conf.Postgres.Password,
+		Dbname:   conf.Postgres.DBName,
 	}
 
 	dbConn, err := repository.Connection(confPostgres)
@@ -38,52 +70,145 @@ func Run(logger *logrus.Logger) error {
 		return err
 	}
 	logger.Infof("Connected to PostgreSQL")
+	defer closeDB(logger, dbConn)
 
-	err = runMigrations(logger, dbConn)
+	err = runMigrations(logger, dbConn, conf.Migrations)
 	if err != nil {
 		logger.Errorf("can't run migrations")
 		return err
 	}
 
-	userRepo := user.NewRepository(dbConn, logger)
-	taskRepo := task.NewRepository(dbConn, logger)
+	cipher, err := encryption.NewCipher(encryption.Config{
+		Key:      conf.Encryption.PassportKey,
+		IndexKey: conf.Encryption.PassportIndexKey,
+	})
+	if err != nil {
+		logger.Errorf("Error loading passport encryption keys")
+		return err
+	}
+
+	queryTimeout := conf.Postgres.QueryTimeout
+	userRepo := user.NewRepository(dbConn, cipher, queryTimeout, logger)
+	taskRepo := task.NewRepository(dbConn, queryTimeout, logger)
+	projectRepo := project.NewRepository(dbConn, queryTimeout, logger)
+	clientRepo := clientRepo.NewRepository(dbConn, queryTimeout, logger)
+	reportRepo := report.NewRepository(dbConn, queryTimeout, logger)
+	teamRepo := team.NewRepository(dbConn, queryTimeout, logger)
+	invoiceRepo := invoiceRepo.NewRepository(dbConn, queryTimeout, logger)
+	organizationRepo := organizationRepo.NewRepository(dbConn, queryTimeout, logger)
+	metrics.Register(collectors.NewDBStatsCollector(dbConn, confPostgres.Dbname), metrics.NewTimerCollector(taskRepo))
+	healthRepo := healthRepo.NewRepository(dbConn, latestMigration(logger, conf.Migrations.Dir), logger)
+
+	err = userRepo.EncryptPassports(context.Background())
+	if err != nil {
+		logger.Errorf("can't encrypt stored passports")
+		return err
+	}
 
-	userInf, err := client.NewClient(os.Getenv("INFO_SERVER"), logger)
+	userInf, err := client.NewClient(conf.InfoServer.URL, logger)
 	if err != nil {
 		return err
 	}
+	defer userInf.Close()
 
 	userService := userService.NewService(userRepo, userInf, logger)
 	taskService := taskService.NewService(taskRepo, logger)
+	projectService := projectService.NewService(projectRepo, logger)
+	clientService := clientService.NewService(clientRepo, logger)
+	reportService := reportService.NewService(reportRepo, logger)
+	teamService := teamService.NewService(teamRepo, logger)
+	invoiceService := invoiceService.NewService(invoiceRepo, logger)
+	organizationService := organizationService.NewService(organizationRepo, userService, logger)
 
-	srv := server.NewServer(userService, taskService, logger)
+	confAuth := authService.Config{
+		Secret:       conf.Auth.JWTSecret,
+		AccessTTL:    conf.Auth.AccessTokenTTL,
+		RefreshTTL:   conf.Auth.RefreshTokenTTL,
+		Organization: uuid.MustParse(conf.Auth.RegistrationOrganization),
+	}
+	authService := authService.NewService(userRepo, userService, confAuth, logger)
+
+	var infoProbe healthService.Prober
+	if conf.InfoServer.Probe {
+		infoProbe = userInf
+	}
+	healthService := healthService.NewService(healthRepo, infoProbe, logger)
 
-	logger.Infof("Running server on port %s", os.Getenv("PORT"))
-	err = http.ListenAndServe(":"+os.Getenv("PORT"), srv.Handlers())
+	srv := server.NewServer(userService, taskService, projectService, clientService, reportService, teamService,
+		invoiceService, organizationService, authService, healthService, logger)
+
+	confServer := conf.Server
+	httpServer := &http.Server{
+		Addr:         ":" + strconv.Itoa(confServer.Port),
+		Handler:      srv.Handlers(),
+		ReadTimeout:  confServer.ReadTimeout,
+		WriteTimeout: confServer.WriteTimeout,
+		IdleTimeout:  confServer.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Infof("Running server on port %d", confServer.Port)
+		serverErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		logger.Errorf("Error starting server: %v", err)
+		return models.ErrServerFailed
+	case <-ctx.Done():
+		stop()
+	}
+
+	// Connections are drained before the deferred calls close the people
+	// info client and then the database, so in-flight requests can finish.
+	logger.Infof("Shutting down server, waiting up to %v for open requests", confServer.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), confServer.ShutdownTimeout)
+	defer cancel()
+
+	err = httpServer.Shutdown(shutdownCtx)
 	if err != nil {
-		logger.Errorf("Error starting server")
+		logger.Errorf("Error shutting down server: %v", err)
+		return models.ErrShutdownFailed
+	}
+	if err = <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		logger.Errorf("Error running server: %v", err)
 		return models.ErrServerFailed
 	}
+	logger.Infof("Server stopped")
 
-	return err
+	return nil
 }
 
-func runMigrations(logger *logrus.Logger, dbConn *sql.DB) error {
-	upMigration, err := strconv.ParseBool(os.Getenv("RUN_MIGRATION"))
+// flushTraces runs last so spans of the shutdown itself are exported too.
+func flushTraces(logger *logrus.Logger, shutdown func(context.Context) error, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := shutdown(ctx)
 	if err != nil {
-		return err
+		logger.Errorf("Error flushing traces: %v", err)
 	}
+}
 
-	if !upMigration {
-		return nil
+func closeDB(logger *logrus.Logger, dbConn *sql.DB) {
+	err := dbConn.Close()
+	if err != nil {
+		logger.Errorf("Error closing database connection: %v", err)
+		return
 	}
+	logger.Infof("Closed PostgreSQL connection")
+}
 
-	migrationDir := os.Getenv("MIGRATION_DIR")
-	if migrationDir == "" {
-		logger.Infof("no migration dir provided; skipping migrations")
+func runMigrations(logger *logrus.Logger, dbConn *sql.DB, conf config.Migrations) error {
+	if !conf.Run {
 		return nil
 	}
-	err = goose.Up(dbConn, os.Getenv("MIGRATION_DIR"))
+
+	err := goose.Up(dbConn, conf.Dir)
 	if err != nil {
 		return err
 	}
@@ -91,3 +216,19 @@ func runMigrations(logger *logrus.Logger, dbConn *sql.DB) error {
 
 	return nil
 }
+
+// latestMigration returns the version of the newest migration in dir, or 0 if
+// it can't be determined, which turns the readiness check off.
+func latestMigration(logger *logrus.Logger, dir string) int64 {
+	migrations, err := goose.CollectMigrations(dir, 0, math.MaxInt64)
+	if err != nil {
+		logger.Warnf("can't collect migrations from %q, skipping migration readiness check: %v", dir, err)
+		return 0
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		logger.Warnf("no migrations in %q, skipping migration readiness check", dir)
+		return 0
+	}
+	return last.Version
+}
@@ -2,37 +2,84 @@ package client
 
 import (
 	"context"
+	"fmt"
 	user_data "github.com/VikaPaz/time_tracker/internal/clients/gen"
+	"github.com/VikaPaz/time_tracker/internal/metrics"
 	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 //go:generate oapi-codegen -package=user_data -generate=types,client,spec  -o ./gen/user_data_client.go ./user_data.yaml
 
 type UserInfo struct {
-	client *user_data.ClientWithResponses
-	server string
-	log    *logrus.Logger
+	client     *user_data.ClientWithResponses
+	httpClient *http.Client
+	transport  *http.Transport
+	server     string
+	log        *logrus.Logger
 }
 
+// requestTimeout bounds a single call to the people info server.
+const requestTimeout = 10 * time.Second
+
 func NewClient(host string, logger *logrus.Logger) (*UserInfo, error) {
-	client, err := user_data.NewClientWithResponses(host)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	httpClient := &http.Client{
+		Transport: transport,
+		Timeout:   requestTimeout,
+	}
+
+	client, err := user_data.NewClientWithResponses(host, user_data.WithHTTPClient(httpClient),
+		user_data.WithRequestEditorFn(tracing.InjectHeaders))
 	if err != nil {
 		logger.Error(err)
 		return &UserInfo{}, models.ErrClientFailed
 	}
 	return &UserInfo{
-			client: client,
-			server: host,
-			log:    logger,
+			client:     client,
+			httpClient: httpClient,
+			transport:  transport,
+			server:     host,
+			log:        logger,
 		},
 		nil
 }
 
+// Ping checks that the people info server answers. Any response below 500
+// counts, the server has no dedicated health endpoint.
+func (u *UserInfo) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.server, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("people info server responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Close releases idle connections to the people info server. It is called
+// once the HTTP server has drained, so no request is using the client.
+func (u *UserInfo) Close() {
+	u.transport.CloseIdleConnections()
+}
+
 func (u *UserInfo) GetInf(ctx context.Context, p models.CreateUserRequest) (models.User, error) {
-	u.log.Debugf("Validating passport %v", p.PassportNumber)
+	ctx, span := tracing.StartClient(ctx, "UserInfo.GetInf")
+	defer span.End()
+
+	u.log.WithContext(ctx).Debugf("Validating passport")
 	passport := *p.PassportNumber
 	if passport == "" {
 		return models.User{}, models.ErrInvalidPassword
@@ -55,11 +102,21 @@ func (u *UserInfo) GetInf(ctx context.Context, p models.CreateUserRequest) (mode
 		PassportNumber: number,
 	}
 
-	u.log.Debugf("Getting user information  with %v", params)
+	u.log.WithContext(ctx).Debugf("Getting user information")
 	resp, err := u.client.GetInfoWithResponse(ctx, &params)
 	if err != nil {
+		metrics.PeopleInfoCalls.WithLabelValues(metrics.OutcomeError).Inc()
 		return models.User{}, err
 	}
+	if resp.JSON200 == nil {
+		outcome := metrics.OutcomeClientError
+		if resp.StatusCode() >= http.StatusInternalServerError {
+			outcome = metrics.OutcomeServerError
+		}
+		metrics.PeopleInfoCalls.WithLabelValues(outcome).Inc()
+		return models.User{}, fmt.Errorf("%w: %s", models.ErrPeopleInfoResponse, resp.Status())
+	}
+	metrics.PeopleInfoCalls.WithLabelValues(metrics.OutcomeSuccess).Inc()
 	userInfo := models.User{
 		Name:       &resp.JSON200.Name,
 		Surname:    &resp.JSON200.Surname,
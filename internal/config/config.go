@@ -0,0 +1,262 @@
+// Package config loads the service configuration.
+//
+// Values come from, in increasing order of precedence: built-in defaults, an
+// optional YAML or TOML file, a .env file in the working directory and the
+// environment. Environment variables carry the TT_ prefix, e.g.
+// TT_POSTGRES_HOST for postgres.host.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prepended to the env tag of every field.
+const EnvPrefix = "TT_"
+
+type Config struct {
+	Log        Log        `yaml:"log" toml:"log"`
+	Server     Server     `yaml:"server" toml:"server"`
+	Postgres   Postgres   `yaml:"postgres" toml:"postgres"`
+	Migrations Migrations `yaml:"migrations" toml:"migrations"`
+	Auth       Auth       `yaml:"auth" toml:"auth"`
+	Encryption Encryption `yaml:"encryption" toml:"encryption"`
+	InfoServer InfoServer `yaml:"info_server" toml:"info_server"`
+	Tracing    Tracing    `yaml:"tracing" toml:"tracing"`
+}
+
+type Log struct {
+	// Level is a logrus level: trace, debug, info, warn, error, fatal or panic.
+	Level string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	// Format is json or text.
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+type Server struct {
+	Port            int           `yaml:"port" toml:"port" env:"SERVER_PORT"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+type Postgres struct {
+	Host     string `yaml:"host" toml:"host" env:"POSTGRES_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"POSTGRES_PORT"`
+	User     string `yaml:"user" toml:"user" env:"POSTGRES_USER"`
+	Password string `yaml:"password" toml:"password" env:"POSTGRES_PASSWORD" secret:"true"`
+	DBName   string `yaml:"db_name" toml:"db_name" env:"POSTGRES_DB_NAME"`
+	// QueryTimeout bounds every repository call made while serving a request.
+	QueryTimeout time.Duration `yaml:"query_timeout" toml:"query_timeout" env:"POSTGRES_QUERY_TIMEOUT"`
+}
+
+type Migrations struct {
+	Run bool   `yaml:"run" toml:"run" env:"MIGRATIONS_RUN"`
+	Dir string `yaml:"dir" toml:"dir" env:"MIGRATIONS_DIR"`
+}
+
+type Auth struct {
+	JWTSecret       string        `yaml:"jwt_secret" toml:"jwt_secret" env:"AUTH_JWT_SECRET" secret:"true"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl" env:"AUTH_ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl" env:"AUTH_REFRESH_TOKEN_TTL"`
+	// RegistrationOrganization is the ID of the organization self-registered
+	// users join. Users invited by an admin join the admin's organization.
+	RegistrationOrganization string `yaml:"registration_organization" toml:"registration_organization" env:"AUTH_REGISTRATION_ORGANIZATION"`
+}
+
+type Encryption struct {
+	// PassportKey and PassportIndexKey are base64 encoded 32 byte keys.
+	PassportKey      string `yaml:"passport_key" toml:"passport_key" env:"ENCRYPTION_PASSPORT_KEY" secret:"true"`
+	PassportIndexKey string `yaml:"passport_index_key" toml:"passport_index_key" env:"ENCRYPTION_PASSPORT_INDEX_KEY" secret:"true"`
+}
+
+type InfoServer struct {
+	URL string `yaml:"url" toml:"url" env:"INFO_SERVER_URL"`
+	// Probe adds the people info server to the readiness check.
+	Probe bool `yaml:"probe" toml:"probe" env:"INFO_SERVER_PROBE"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector.
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	OTLPInsecure bool   `yaml:"otlp_insecure" toml:"otlp_insecure" env:"TRACING_OTLP_INSECURE"`
+	ServiceName  string `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+// Error lists every problem found while loading the configuration, so all
+// of them can be fixed in one go.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+func Default() Config {
+	return Config{
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Server: Server{
+			Port:            8000,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    60 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Postgres: Postgres{
+			Host:         "localhost",
+			Port:         5432,
+			QueryTimeout: 5 * time.Second,
+		},
+		Migrations: Migrations{
+			Run: true,
+			Dir: "migrations",
+		},
+		Auth: Auth{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+
+			RegistrationOrganization: models.DefaultOrganizationID.String(),
+		},
+		Tracing: Tracing{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4318",
+			ServiceName:  "time_tracker",
+		},
+	}
+}
+
+// Load builds the configuration from the file at path, if any, and the
+// environment. The returned config is filled in even when an *Error is
+// returned, which lets --print-config show what was loaded.
+func Load(path string) (Config, error) {
+	conf := Default()
+	problems := []string{}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		problems = append(problems, fmt.Sprintf(".env: %v", err))
+	}
+
+	if path != "" {
+		problems = append(problems, loadFile(path, &conf)...)
+	}
+	problems = append(problems, loadEnv(&conf)...)
+	problems = append(problems, conf.validate()...)
+
+	if len(problems) > 0 {
+		return conf, &Error{Problems: problems}
+	}
+	return conf, nil
+}
+
+func loadFile(path string, conf *Config) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", path, err)}
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err = decoder.Decode(conf); err != nil && !errors.Is(err, io.EOF) {
+			return []string{fmt.Sprintf("%s: %v", path, err)}
+		}
+	case ".toml":
+		meta, err := toml.NewDecoder(file).Decode(conf)
+		if err != nil {
+			return []string{fmt.Sprintf("%s: %v", path, err)}
+		}
+		var problems []string
+		for _, key := range meta.Undecoded() {
+			problems = append(problems, fmt.Sprintf("%s: unknown key %s", path, key))
+		}
+		return problems
+	default:
+		return []string{fmt.Sprintf("%s: unsupported format, expected .yaml, .yml or .toml", path)}
+	}
+	return nil
+}
+
+func (c Config) validate() []string {
+	var problems []string
+	required := func(key, value string) {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("%s%s: required", EnvPrefix, key))
+		}
+	}
+	port := func(key string, value int) {
+		if value <= 0 || value > 65535 {
+			problems = append(problems, fmt.Sprintf("%s%s: port %d out of range", EnvPrefix, key, value))
+		}
+	}
+	positive := func(key string, value time.Duration) {
+		if value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s%s: must be positive, got %v", EnvPrefix, key, value))
+		}
+	}
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, fmt.Sprintf("%sLOG_LEVEL: %v", EnvPrefix, err))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		problems = append(problems, fmt.Sprintf("%sLOG_FORMAT: expected json or text, got %q", EnvPrefix, c.Log.Format))
+	}
+
+	port("SERVER_PORT", c.Server.Port)
+	positive("SERVER_READ_TIMEOUT", c.Server.ReadTimeout)
+	positive("SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout)
+	positive("SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout)
+	positive("SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+
+	required("POSTGRES_HOST", c.Postgres.Host)
+	port("POSTGRES_PORT", c.Postgres.Port)
+	required("POSTGRES_USER", c.Postgres.User)
+	required("POSTGRES_DB_NAME", c.Postgres.DBName)
+	positive("POSTGRES_QUERY_TIMEOUT", c.Postgres.QueryTimeout)
+
+	if c.Migrations.Run {
+		required("MIGRATIONS_DIR", c.Migrations.Dir)
+	}
+
+	required("AUTH_JWT_SECRET", c.Auth.JWTSecret)
+	positive("AUTH_ACCESS_TOKEN_TTL", c.Auth.AccessTokenTTL)
+	positive("AUTH_REFRESH_TOKEN_TTL", c.Auth.RefreshTokenTTL)
+	if _, err := uuid.Parse(c.Auth.RegistrationOrganization); err != nil {
+		problems = append(problems, fmt.Sprintf("%sAUTH_REGISTRATION_ORGANIZATION: %v", EnvPrefix, err))
+	}
+
+	required("ENCRYPTION_PASSPORT_KEY", c.Encryption.PassportKey)
+	required("ENCRYPTION_PASSPORT_INDEX_KEY", c.Encryption.PassportIndexKey)
+
+	required("INFO_SERVER_URL", c.InfoServer.URL)
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		required("TRACING_OTLP_ENDPOINT", c.Tracing.OTLPEndpoint)
+	default:
+		problems = append(problems, fmt.Sprintf("%sTRACING_EXPORTER: expected none, stdout or otlp, got %q",
+			EnvPrefix, c.Tracing.Exporter))
+	}
+	required("TRACING_SERVICE_NAME", c.Tracing.ServiceName)
+	return problems
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnv overrides fields that have an env tag with the value of the
+// prefixed environment variable, if it is set.
+func loadEnv(conf *Config) []string {
+	var problems []string
+	walk(reflect.ValueOf(conf).Elem(), func(field reflect.StructField, value reflect.Value) {
+		name, ok := field.Tag.Lookup("env")
+		if !ok {
+			return
+		}
+		name = EnvPrefix + name
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := set(value, env); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	})
+	return problems
+}
+
+// Redacted returns a copy of the config with secrets replaced, safe to print
+// or log.
+func (c Config) Redacted() Config {
+	walk(reflect.ValueOf(&c).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString(redacted)
+		}
+	})
+	return c
+}
+
+func walk(v reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if value.Kind() == reflect.Struct {
+			walk(value, fn)
+			continue
+		}
+		fn(field, value)
+	}
+}
+
+func set(value reflect.Value, env string) error {
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", env)
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(env)
+	case reflect.Int:
+		n, err := strconv.Atoi(env)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", env)
+		}
+		value.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", env)
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %v", value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package encryption protects personal data stored by the repositories.
+//
+// Values are envelope encrypted: every value gets its own random data key,
+// the data key is sealed with the key encryption key from configuration and
+// stored next to the ciphertext. Exact-match lookups go through a blind
+// index, an HMAC of the normalized value under a separate key, so the
+// ciphertext itself never has to be deterministic.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"strings"
+	"unicode"
+)
+
+const (
+	version = "v1:"
+	keySize = 32
+)
+
+type Config struct {
+	// Key is the base64 encoded 32 byte key encryption key.
+	Key string
+	// IndexKey is the base64 encoded key of the blind index. It must differ
+	// from Key.
+	IndexKey string
+}
+
+type Cipher struct {
+	kek      cipher.AEAD
+	indexKey []byte
+}
+
+func NewCipher(conf Config) (*Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(conf.Key)
+	if err != nil || len(key) != keySize {
+		return nil, models.ErrInvalidEncryptionKey
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(conf.IndexKey)
+	if err != nil || len(indexKey) < keySize || hmac.Equal(key, indexKey) {
+		return nil, models.ErrInvalidEncryptionKey
+	}
+
+	kek, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{
+		kek:      kek,
+		indexKey: indexKey,
+	}, nil
+}
+
+// Encrypt seals value under a fresh data key. The result is
+// "v1:" + base64(sealed data key || nonce || ciphertext).
+func (c *Cipher) Encrypt(value string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	sealedKey, err := seal(c.kek, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := seal(aead, []byte(value))
+	if err != nil {
+		return "", err
+	}
+
+	return version + base64.StdEncoding.EncodeToString(append(sealedKey, sealedValue...)), nil
+}
+
+// Decrypt reverses Encrypt. Values without the version prefix were written
+// before encryption was introduced and are returned unchanged.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, version) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, version))
+	if err != nil {
+		return "", models.ErrDecryptValue
+	}
+
+	sealedKeySize := c.kek.NonceSize() + keySize + c.kek.Overhead()
+	if len(data) < sealedKeySize {
+		return "", models.ErrDecryptValue
+	}
+	dataKey, err := open(c.kek, data[:sealedKeySize])
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(aead, data[sealedKeySize:])
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// BlindIndex returns a deterministic digest of value for exact-match lookup.
+// Whitespace is ignored, so "1234 567890" and "1234567890" match.
+func (c *Cipher) BlindIndex(value string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, value)
+
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plain []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, models.ErrDecryptValue
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, models.ErrDecryptValue
+	}
+	return plain, nil
+}
@@ -0,0 +1,167 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"strings"
+	"testing"
+)
+
+func key(b byte, size int) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), size)))
+}
+
+func newTestCipher(t *testing.T) *Cipher {
+	t.Helper()
+	c, err := NewCipher(Config{Key: key('k', keySize), IndexKey: key('i', keySize)})
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	return c
+}
+
+func TestNewCipher(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Config
+		ok   bool
+	}{
+		{"valid", Config{Key: key('k', keySize), IndexKey: key('i', keySize)}, true},
+		{"longer index key", Config{Key: key('k', keySize), IndexKey: key('i', 64)}, true},
+		{"short key", Config{Key: key('k', 16), IndexKey: key('i', keySize)}, false},
+		{"long key", Config{Key: key('k', 48), IndexKey: key('i', keySize)}, false},
+		{"short index key", Config{Key: key('k', keySize), IndexKey: key('i', 16)}, false},
+		{"same keys", Config{Key: key('k', keySize), IndexKey: key('k', keySize)}, false},
+		{"not base64", Config{Key: "not base64!", IndexKey: key('i', keySize)}, false},
+		{"empty", Config{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCipher(tt.conf)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, models.ErrInvalidEncryptionKey) {
+				t.Fatalf("got %v, want %v", err, models.ErrInvalidEncryptionKey)
+			}
+		})
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	c := newTestCipher(t)
+	tests := []string{
+		"",
+		"1234 567890",
+		"AB1234567",
+		"паспорт 45 06 123456",
+		strings.Repeat("9", 1000),
+	}
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			encrypted, err := c.Encrypt(value)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if !strings.HasPrefix(encrypted, version) {
+				t.Fatalf("ciphertext %q lacks the %q prefix", encrypted, version)
+			}
+			if value != "" && strings.Contains(encrypted, value) {
+				t.Fatalf("ciphertext %q contains the plain text", encrypted)
+			}
+
+			again, err := c.Encrypt(value)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if again == encrypted {
+				t.Fatal("encrypting twice gave the same ciphertext")
+			}
+
+			decrypted, err := c.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if decrypted != value {
+				t.Fatalf("got %q, want %q", decrypted, value)
+			}
+		})
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	c := newTestCipher(t)
+	encrypted, err := c.Encrypt("1234 567890")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, version))
+	flipped := append([]byte{}, data...)
+	flipped[len(flipped)-1] ^= 1
+
+	other, err := NewCipher(Config{Key: key('o', keySize), IndexKey: key('i', keySize)})
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		cipher *Cipher
+		value  string
+		want   string
+		err    error
+	}{
+		{"legacy plain text", c, "1234 567890", "1234 567890", nil},
+		{"legacy empty", c, "", "", nil},
+		{"encrypted", c, encrypted, "1234 567890", nil},
+		{"tampered", c, version + base64.StdEncoding.EncodeToString(flipped), "", models.ErrDecryptValue},
+		{"truncated", c, version + base64.StdEncoding.EncodeToString(data[:10]), "", models.ErrDecryptValue},
+		{"not base64", c, version + "not base64!", "", models.ErrDecryptValue},
+		{"other key", other, encrypted, "", models.ErrDecryptValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.value)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	c := newTestCipher(t)
+	other, err := NewCipher(Config{Key: key('k', keySize), IndexKey: key('o', keySize)})
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		a, b  string
+		other bool
+		equal bool
+	}{
+		{"same value", "1234 567890", "1234 567890", false, true},
+		{"whitespace ignored", "1234 567890", " 1234\t567890 ", false, true},
+		{"case ignored", "ab123456", "AB123456", false, true},
+		{"different value", "1234 567890", "1234 567891", false, false},
+		{"different index key", "1234 567890", "1234 567890", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := c
+			if tt.other {
+				b = other
+			}
+			got := c.BlindIndex(tt.a) == b.BlindIndex(tt.b)
+			if got != tt.equal {
+				t.Fatalf("indexes of %q and %q equal: %t, want %t", tt.a, tt.b, got, tt.equal)
+			}
+		})
+	}
+}
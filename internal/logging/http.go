@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength caps IDs taken from clients so they can't flood the logs.
+const maxRequestIDLength = 128
+
+// Middleware assigns every request an ID, taken from the X-Request-ID header
+// when the client sent one, returns it in the response and logs the request
+// once it is served.
+func Middleware(logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > maxRequestIDLength {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := withRequest(r.Context(), id)
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			entry := logger.WithContext(ctx).WithFields(logrus.Fields{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      status,
+				"bytes":       ww.BytesWritten(),
+				"duration_ms": time.Since(start).Milliseconds(),
+			})
+			if rctx := chi.RouteContext(ctx); rctx == nil || rctx.RoutePattern() == "" {
+				entry = entry.WithField("route", "unmatched")
+			}
+			if status >= http.StatusInternalServerError {
+				entry.Error("Request failed")
+				return
+			}
+			entry.Info("Request served")
+		})
+	}
+}
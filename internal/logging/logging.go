@@ -0,0 +1,95 @@
+// Package logging adds request-scoped fields to log entries and keeps personal
+// data out of them.
+//
+// Code logs through logger.WithContext(ctx); Hook then adds the request ID,
+// the authenticated user, the chi route and the trace ID found in ctx.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+type requestKey struct{}
+
+// request is shared by everything serving one request, so the user found by
+// the auth middleware also ends up on the access log line written above it.
+type request struct {
+	id     string
+	userID uuid.UUID
+}
+
+// sensitiveKeys are field names whose values are never logged.
+var sensitiveKeys = []string{"passport", "password", "token", "address"}
+
+func withRequest(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{id: id})
+}
+
+// RequestID returns the ID of the request being served, or "" outside of
+// Middleware.
+func RequestID(ctx context.Context) string {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.id
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the request being served.
+func SetUserID(ctx context.Context, id uuid.UUID) {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		req.userID = id
+	}
+}
+
+type Hook struct{}
+
+func (Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (Hook) Fire(entry *logrus.Entry) error {
+	if ctx := entry.Context; ctx != nil {
+		if req, ok := ctx.Value(requestKey{}).(*request); ok {
+			entry.Data["request_id"] = req.id
+			if req.userID != uuid.Nil {
+				entry.Data["user_id"] = req.userID.String()
+			}
+		}
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			entry.Data["route"] = rctx.RoutePattern()
+		}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+			entry.Data["trace_id"] = spanContext.TraceID().String()
+		}
+	}
+
+	for key, value := range entry.Data {
+		entry.Data[key] = redact(key, value)
+	}
+	return nil
+}
+
+// redact replaces personal data in a field. Users are rendered through their
+// String method, which leaves PII out, instead of being marshalled field by
+// field by the JSON formatter.
+func redact(key string, value interface{}) interface{} {
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(strings.ToLower(key), sensitive) {
+			return models.Redacted
+		}
+	}
+
+	switch v := value.(type) {
+	case models.User, *models.User, []models.User, models.CreateUserRequest, *models.CreateUserRequest,
+		models.LoginRequest, *models.LoginRequest, models.FilterRequest, models.FilterResponse:
+		return fmt.Sprintf("%v", v)
+	}
+	return value
+}
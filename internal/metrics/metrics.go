@@ -0,0 +1,64 @@
+// Package metrics exposes Prometheus metrics of the service on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "time_tracker"
+
+// Timer events counted by TimerEvents.
+const (
+	TimerStarted = "start"
+	TimerStopped = "stop"
+	TimerPaused  = "pause"
+	TimerResumed = "resume"
+)
+
+// Outcomes of calls to the people info server counted by PeopleInfoCalls.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeError       = "error"
+	OutcomeClientError = "client_error"
+	OutcomeServerError = "server_error"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and chi route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	PeopleInfoCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "people_info_requests_total",
+		Help:      "Calls to the people info server by outcome.",
+	}, []string{"outcome"})
+
+	TimerEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "timer_events_total",
+		Help:      "Timer starts, stops, pauses and resumes.",
+	}, []string{"event"})
+)
+
+// Register adds collectors that depend on runtime objects, such as the
+// database pool, to the default registry.
+func Register(collectors ...prometheus.Collector) {
+	prometheus.MustRegister(collectors...)
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type RunningTimers interface {
+	RunningTimers() (int64, error)
+}
+
+// TimerCollector reports the number of running timers, read from the
+// database on every scrape.
+type TimerCollector struct {
+	source  RunningTimers
+	running *prometheus.Desc
+}
+
+func NewTimerCollector(source RunningTimers) *TimerCollector {
+	return &TimerCollector{
+		source: source,
+		running: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "running_timers"),
+			"Timers that are currently running.", nil, nil),
+	}
+}
+
+func (c *TimerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.running
+}
+
+func (c *TimerCollector) Collect(ch chan<- prometheus.Metric) {
+	running, err := c.source.RunningTimers()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.running, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(running))
+}
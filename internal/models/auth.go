@@ -0,0 +1,55 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"strings"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleManager  = "manager"
+	RoleEmployee = "employee"
+)
+
+// Caller is the authenticated user a request is made on behalf of.
+type Caller struct {
+	ID             uuid.UUID
+	Role           string
+	OrganizationID uuid.UUID
+	TeamID         *uuid.UUID
+}
+
+type LoginRequest struct {
+	PassportNumber *string `json:"passportNumber"`
+	Password       *string `json:"password"`
+}
+
+func (l LoginRequest) String() string {
+	var b strings.Builder
+	b.WriteString("{")
+	writeRedacted(&b, "PassportNumber", l.PassportNumber)
+	writeRedacted(&b, "Password", l.Password)
+	b.WriteString("}")
+	return b.String()
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type Credentials struct {
+	Caller       Caller
+	PasswordHash string
+}
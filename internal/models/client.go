@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+type Client struct {
+	ID             *uuid.UUID `json:"id,omitempty"`
+	Name           *string    `json:"name,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+}
+
+type ClientFilterRequest struct {
+	Fields Client `json:"fields,omitempty"`
+	Limit  uint64 `json:"limit,omitempty"`
+	Offset uint64 `json:"offset,omitempty"`
+}
+
+type ClientFilterResponse struct {
+	Clients []Client
+	Total   int64
+}
+
+type DeleteClientRequest struct {
+	ID             uuid.UUID `json:"id,omitempty"`
+	OrganizationID uuid.UUID `json:"-"`
+}
@@ -6,7 +6,24 @@ var (
 	ErrLoadEnvFailed      = errors.New("failed to load environment")
 	ErrConnectionDBFailed = errors.New("failed to connect to database")
 	ErrServerFailed       = errors.New("failed to connect to server")
+	ErrShutdownFailed     = errors.New("failed to shut down server gracefully")
 	ErrClientFailed       = errors.New("failed to create client")
+	ErrPeopleInfoResponse = errors.New("unexpected response from people info server")
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+)
+
+var (
+	ErrInvalidEncryptionKey = errors.New("invalid encryption key")
+	ErrDecryptValue         = errors.New("failed to decrypt value")
+	ErrInvalidTraceExporter = errors.New("invalid trace exporter")
+)
+
+var (
+	ErrMigrationsBehind = errors.New("database migrations are not at the latest version")
+	ErrBuildInfo        = errors.New("build information is not available")
 )
 
 var (
@@ -16,13 +33,112 @@ var (
 	ErrGetUserResponse        = errors.New("failed to get user")
 	ErrUserExists             = errors.New("user already exists")
 	ErrCreateUserResponse     = errors.New("failed to create user")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrInvalidSort            = errors.New("invalid sort")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid passport number or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrIssueToken         = errors.New("failed to issue token")
+	ErrAccessDenied       = errors.New("access denied")
+	ErrInvalidRole        = errors.New("invalid role")
+)
+
+const (
+	ReasonRoleRequired  = "role_required"
+	ReasonNotOwner      = "not_owner"
+	ReasonNotTeamMember = "not_team_member"
+)
+
+// AccessError is returned when the caller may not perform an operation.
+// Reason is a stable machine-readable code, Detail is meant for humans.
+type AccessError struct {
+	Reason string
+	Detail string
+}
+
+func (e *AccessError) Error() string {
+	return ErrAccessDenied.Error() + ": " + e.Detail
+}
+
+func (e *AccessError) Is(target error) bool {
+	return target == ErrAccessDenied
+}
+
+var (
+	ErrClientDeleteResponse     = errors.New("failed to delete client")
+	ErrChangeClientInfoResponse = errors.New("failed to change client info")
+	ErrGetClientResponse        = errors.New("failed to get client")
+	ErrCreateClientResponse     = errors.New("failed to create client")
+	ErrClientNotFound           = errors.New("client not found")
+)
+
+var (
+	ErrProjectDeleteResponse     = errors.New("failed to delete project")
+	ErrChangeProjectInfoResponse = errors.New("failed to change project info")
+	ErrGetProjectResponse        = errors.New("failed to get project")
+	ErrCreateProjectResponse     = errors.New("failed to create project")
+	ErrProjectNotFound           = errors.New("project not found")
+)
+
+var (
+	ErrCreateOrganization = errors.New("failed to create organization")
+)
+
+var (
+	ErrTeamDeleteResponse     = errors.New("failed to delete team")
+	ErrChangeTeamInfoResponse = errors.New("failed to change team info")
+	ErrGetTeamResponse        = errors.New("failed to get team")
+	ErrCreateTeamResponse     = errors.New("failed to create team")
+	ErrTeamNotFound           = errors.New("team not found")
 )
 
 var (
 	ErrCreateTaskResponse = errors.New("failed to create task")
 	ErrGetTaskResponse    = errors.New("failed to get task")
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrUpdateTask         = errors.New("failed to update task")
+	ErrInvalidTaskStatus  = errors.New("invalid task status")
+	ErrTaskClosed         = errors.New("task is completed or archived")
+	ErrInvalidTag         = errors.New("invalid tag")
+	ErrInvalidRate        = errors.New("invalid hourly rate")
+	ErrInvalidTaxRate     = errors.New("invalid tax rate")
+	ErrNothingToInvoice   = errors.New("no unbilled labor time to invoice")
+	ErrInvoiceCurrencies  = errors.New("labor time is billed in several currencies")
+	ErrInvoiceNotFound    = errors.New("invoice not found")
+	ErrCreateInvoice      = errors.New("failed to create invoice")
+	ErrGetInvoice         = errors.New("failed to get invoice")
+	ErrTagNotFound        = errors.New("tag not found")
+	ErrTagTask            = errors.New("failed to tag task")
 	ErrCheckTimerStatus   = errors.New("failed to check timer status")
 	ErrTimerStarted       = errors.New("timer already started")
 	ErrStartTimer         = errors.New("failed to start timer")
 	ErrStopTimer          = errors.New("failed to stop timer")
+	ErrTimerNotStarted    = errors.New("timer is not started")
+	ErrTimerNotRunning    = errors.New("timer is not running")
+	ErrTimerNotPaused     = errors.New("timer is not paused")
+	ErrPauseTimer         = errors.New("failed to pause timer")
+	ErrResumeTimer        = errors.New("failed to resume timer")
+	ErrGetSegments        = errors.New("failed to get timer segments")
+)
+
+var (
+	ErrCreateTimeEntry     = errors.New("failed to create time entry")
+	ErrGetTimeEntry        = errors.New("failed to get time entry")
+	ErrChangeTimeEntry     = errors.New("failed to change time entry")
+	ErrDeleteTimeEntry     = errors.New("failed to delete time entry")
+	ErrTimeEntryNotFound   = errors.New("time entry not found")
+	ErrTimeEntryInvoiced   = errors.New("time entry has been invoiced")
+	ErrInvalidTimeInterval = errors.New("stop time must be after start time")
+	ErrTimeEntryOverlap    = errors.New("time entry overlaps another entry")
+)
+
+var (
+	ErrGetLaborReport  = errors.New("failed to get labor report")
+	ErrInvalidTimeZone = errors.New("invalid time zone")
+	ErrInvalidGroupBy  = errors.New("invalid report grouping")
+	ErrGetTimesheet    = errors.New("failed to get timesheet")
+	ErrInvalidFormat   = errors.New("invalid timesheet format")
 )
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+const (
+	InvoiceJSON = "json"
+	InvoicePDF  = "pdf"
+	InvoiceUBL  = "ubl"
+)
+
+// InvoiceRequest selects the labor time to invoice: finished segments not
+// invoiced yet, of billable tasks in projects of the client, with a rate
+// applying. Segments are billed whole, so only those inside the window are
+// selected.
+type InvoiceRequest struct {
+	ClientID  uuid.UUID  `json:"client_id"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	// Currency restricts the invoice to labor time billed in it. It is
+	// required when the rates of the client's tasks differ in currency.
+	Currency string `json:"currency,omitempty" example:"EUR"`
+	// TaxRate is the percentage of tax added to the subtotal.
+	TaxRate        decimal.Decimal `json:"tax_rate" swaggertype:"string" example:"20"`
+	OrganizationID uuid.UUID       `json:"-"`
+	CreatedBy      uuid.UUID       `json:"-"`
+}
+
+// BillableSegment is a labor_time segment selected for an invoice.
+type BillableSegment struct {
+	ID     uuid.UUID
+	TaskID uuid.UUID
+	Task   string
+	Start  time.Time
+	Stop   time.Time
+	Rate   Rate
+}
+
+// Invoice is frozen once issued: its parties, lines and totals don't follow
+// later changes of names, rates or labor time.
+type Invoice struct {
+	ID          uuid.UUID       `json:"id"`
+	Number      int64           `json:"number"`
+	Seller      string          `json:"seller"`
+	ClientID    *uuid.UUID      `json:"client_id,omitempty"`
+	Client      string          `json:"client"`
+	IssuedAt    time.Time       `json:"issued_at"`
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	Currency    string          `json:"currency"`
+	Lines       []InvoiceLine   `json:"lines"`
+	Subtotal    decimal.Decimal `json:"subtotal" swaggertype:"string" example:"1000.00"`
+	TaxRate     decimal.Decimal `json:"tax_rate" swaggertype:"string" example:"20"`
+	Tax         decimal.Decimal `json:"tax" swaggertype:"string" example:"200.00"`
+	Total       decimal.Decimal `json:"total" swaggertype:"string" example:"1200.00"`
+}
+
+// Reference is the invoice number as printed.
+func (i Invoice) Reference() string {
+	return fmt.Sprintf("INV-%06d", i.Number)
+}
+
+// InvoiceLine bills the labor time of one task at one rate.
+type InvoiceLine struct {
+	TaskID      *uuid.UUID      `json:"task_id,omitempty"`
+	Description string          `json:"description"`
+	Hours       decimal.Decimal `json:"hours" swaggertype:"string" example:"12.5"`
+	UnitPrice   decimal.Decimal `json:"unit_price" swaggertype:"string" example:"80.00"`
+	Amount      decimal.Decimal `json:"amount" swaggertype:"string" example:"1000.00"`
+	// Segments are the labor time segments the line bills. Only a new
+	// invoice has them.
+	Segments []uuid.UUID `json:"-"`
+}
+
+type InvoiceExportRequest struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+	Format         string
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// DefaultOrganizationID is the organization seeded by the migrations.
+// Self-registered users join it unless auth.registration_organization names
+// another one.
+var DefaultOrganizationID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+type Organization struct {
+	ID   *uuid.UUID `json:"id,omitempty"`
+	Name *string    `json:"name,omitempty"`
+}
+
+// CreateOrganizationRequest creates an organization together with its first
+// admin, who then invites everybody else.
+type CreateOrganizationRequest struct {
+	Name  *string           `json:"name,omitempty"`
+	Admin CreateUserRequest `json:"admin"`
+}
+
+type CreateOrganizationResponse struct {
+	Organization Organization `json:"organization"`
+	Admin        User         `json:"admin"`
+}
+
+type Team struct {
+	ID             *uuid.UUID `json:"id,omitempty"`
+	Name           *string    `json:"name,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+}
+
+type TeamFilterRequest struct {
+	Fields Team   `json:"fields,omitempty"`
+	Limit  uint64 `json:"limit,omitempty"`
+	Offset uint64 `json:"offset,omitempty"`
+}
+
+type TeamFilterResponse struct {
+	Teams []Team
+	Total int64
+}
+
+type DeleteTeamRequest struct {
+	ID             uuid.UUID `json:"id,omitempty"`
+	OrganizationID uuid.UUID `json:"-"`
+}
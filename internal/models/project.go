@@ -0,0 +1,30 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+type Project struct {
+	ID             *uuid.UUID `json:"id,omitempty"`
+	Name           *string    `json:"name,omitempty"`
+	ClientID       *uuid.UUID `json:"client_id,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	// HourlyRate applies to tasks of the project without a rate of their own.
+	HourlyRate *Rate `json:"hourly_rate,omitempty"`
+}
+
+type ProjectFilterRequest struct {
+	Fields Project `json:"fields,omitempty"`
+	Limit  uint64  `json:"limit,omitempty"`
+	Offset uint64  `json:"offset,omitempty"`
+}
+
+type ProjectFilterResponse struct {
+	Projects []Project
+	Total    int64
+}
+
+type DeleteProjectRequest struct {
+	ID             uuid.UUID `json:"id,omitempty"`
+	OrganizationID uuid.UUID `json:"-"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"regexp"
+	"time"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Rate is an hourly rate. Users, projects and tasks may have one; the most
+// specific applies to labor time: the task's, then the project's, then the
+// user's. Setting a zero rate removes it.
+type Rate struct {
+	Amount decimal.Decimal `json:"amount" swaggertype:"string" example:"85.00"`
+	// Currency is an ISO 4217 code.
+	Currency string `json:"currency" example:"EUR"`
+}
+
+func (r Rate) IsZero() bool {
+	return r.Amount.IsZero()
+}
+
+func (r Rate) Validate() error {
+	if r.IsZero() {
+		return nil
+	}
+	if r.Amount.IsNegative() {
+		return fmt.Errorf("%w: amount is negative", ErrInvalidRate)
+	}
+	if !r.Amount.Equal(r.Amount.Round(2)) {
+		return fmt.Errorf("%w: amount has more than two decimals", ErrInvalidRate)
+	}
+	if !currencyCode.MatchString(r.Currency) {
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrInvalidRate)
+	}
+	return nil
+}
+
+// key tells rates apart, String drops trailing zeros so 85.00 and 85 match.
+func (r Rate) key() string {
+	return r.Amount.String() + " " + r.Currency
+}
+
+// Earn returns the amount earned by working d at the rate, rounded to cents.
+func (r Rate) Earn(d time.Duration) decimal.Decimal {
+	seconds := decimal.NewFromInt(int64(d / time.Second))
+	return r.Amount.Mul(seconds).Div(decimal.NewFromInt(int64(time.Hour / time.Second))).Round(2)
+}
+
+// Earning is the billable labor time at one rate and what it amounts to.
+type Earning struct {
+	Rate      Rate            `json:"rate"`
+	LaborTime string          `json:"time"`
+	Amount    decimal.Decimal `json:"amount" swaggertype:"string" example:"170.00"`
+}
+
+// Earnings sums billable labor time by rate. The amount of each rate is
+// computed from its total time, so it may differ by a cent from the sum of
+// the rounded amounts of the tasks.
+type Earnings struct {
+	rates  []Rate
+	totals map[string]time.Duration
+}
+
+func (e *Earnings) Add(rate Rate, d time.Duration) {
+	key := rate.key()
+	if e.totals == nil {
+		e.totals = map[string]time.Duration{}
+	}
+	if _, ok := e.totals[key]; !ok {
+		e.rates = append(e.rates, rate)
+	}
+	e.totals[key] += d
+}
+
+// List returns the earnings in the order rates were first added.
+func (e *Earnings) List() []Earning {
+	earnings := make([]Earning, 0, len(e.rates))
+	for _, rate := range e.rates {
+		d := e.totals[rate.key()]
+		earnings = append(earnings, Earning{
+			Rate:      rate,
+			LaborTime: FormatLaborTime(d),
+			Amount:    rate.Earn(d),
+		})
+	}
+	return earnings
+}
+
+// BillableAmount returns what d earns at rate, or nil when the time isn't
+// billable or no rate applies.
+func BillableAmount(billable bool, rate *Rate, d time.Duration) *decimal.Decimal {
+	if !billable || rate == nil {
+		return nil
+	}
+	amount := rate.Earn(d)
+	return &amount
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func rate(amount, currency string) Rate {
+	return Rate{Amount: decimal.RequireFromString(amount), Currency: currency}
+}
+
+func TestRateValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rate Rate
+		ok   bool
+	}{
+		{"valid", rate("85.00", "EUR"), true},
+		{"whole", rate("85", "USD"), true},
+		{"zero clears without currency", rate("0", ""), true},
+		{"negative", rate("-1", "EUR"), false},
+		{"fractions of a cent", rate("85.001", "EUR"), false},
+		{"no currency", rate("85", ""), false},
+		{"lower case currency", rate("85", "eur"), false},
+		{"long currency", rate("85", "EURO"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rate.Validate()
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidRate) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidRate)
+			}
+		})
+	}
+}
+
+func TestRateEarn(t *testing.T) {
+	tests := []struct {
+		rate Rate
+		d    time.Duration
+		want string
+	}{
+		{rate("85", "EUR"), 2 * time.Hour, "170"},
+		{rate("85", "EUR"), 90 * time.Minute, "127.5"},
+		{rate("100", "EUR"), time.Minute, "1.67"},
+		{rate("100", "EUR"), 20 * time.Second, "0.56"},
+		{rate("0.01", "EUR"), time.Minute, "0"},
+		{rate("85", "EUR"), 1500 * time.Millisecond, "0.02"},
+		{rate("85", "EUR"), 0, "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rate.Amount.String()+" "+tt.d.String(), func(t *testing.T) {
+			got := tt.rate.Earn(tt.d)
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEarnings(t *testing.T) {
+	e := Earnings{}
+	e.Add(rate("85.00", "EUR"), time.Hour)
+	e.Add(rate("100", "EUR"), 30*time.Minute)
+	e.Add(rate("85", "EUR"), 30*time.Minute)
+	e.Add(rate("85", "USD"), time.Hour)
+
+	want := []Earning{
+		{Rate: rate("85.00", "EUR"), LaborTime: "hours: 1 minutes: 30", Amount: decimal.RequireFromString("127.5")},
+		{Rate: rate("100", "EUR"), LaborTime: "hours: 0 minutes: 30", Amount: decimal.RequireFromString("50")},
+		{Rate: rate("85", "USD"), LaborTime: "hours: 1 minutes: 0", Amount: decimal.RequireFromString("85")},
+	}
+	got := e.List()
+	if len(got) != len(want) {
+		t.Fatalf("got %d earnings, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Rate.key() != want[i].Rate.key() || got[i].LaborTime != want[i].LaborTime ||
+			!got[i].Amount.Equal(want[i].Amount) {
+			t.Errorf("earning %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if empty := (&Earnings{}).List(); empty == nil || len(empty) != 0 {
+		t.Errorf("got %v for no earnings, want an empty list", empty)
+	}
+}
+
+func TestBillableAmount(t *testing.T) {
+	r := rate("60", "EUR")
+	tests := []struct {
+		name     string
+		billable bool
+		rate     *Rate
+		want     string
+	}{
+		{"billable", true, &r, "30"},
+		{"not billable", false, &r, ""},
+		{"no rate", true, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BillableAmount(tt.billable, tt.rate, 30*time.Minute)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("got %v, want nil", got)
+				}
+				return
+			}
+			if got == nil || !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
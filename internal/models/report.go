@@ -0,0 +1,87 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+const (
+	ReportByDay   = "day"
+	ReportByWeek  = "week"
+	ReportByMonth = "month"
+)
+
+type LaborReportRequest struct {
+	UserID         *uuid.UUID `json:"user_id,omitempty"`
+	TeamID         *uuid.UUID `json:"team_id,omitempty"`
+	GroupBy        string     `json:"group_by"`
+	TimeZone       string     `json:"tz"`
+	StartTime      *time.Time `json:"start_time,omitempty"`
+	EndTime        *time.Time `json:"end_time,omitempty"`
+	OrganizationID *uuid.UUID `json:"-"`
+}
+
+// LaborReportRow is the labor time of one task within one bucket. Bucket is
+// the wall-clock start of the day, week or month in the requested time zone.
+type LaborReportRow struct {
+	Bucket    time.Time
+	TaskID    uuid.UUID
+	Task      *string
+	LaborTime time.Duration
+	Billable  bool
+	Rate      *Rate
+}
+
+type LaborReportResponse struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	GroupBy  string        `json:"group_by"`
+	TimeZone string        `json:"tz"`
+	Buckets  []LaborBucket `json:"buckets"`
+	// Earnings break the billable labor time of all buckets down by rate.
+	Earnings []Earning `json:"earnings"`
+}
+
+type LaborBucket struct {
+	Start     string            `json:"start"`
+	LaborTime string            `json:"time"`
+	Tasks     []LaborBucketTask `json:"tasks"`
+	Earnings  []Earning         `json:"earnings"`
+}
+
+type LaborBucketTask struct {
+	ID        uuid.UUID `json:"id"`
+	Task      *string   `json:"task"`
+	LaborTime string    `json:"time"`
+	Billable  bool      `json:"billable"`
+	Rate      *Rate     `json:"rate,omitempty"`
+	// Amount is only set for billable tasks with a rate.
+	Amount *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"42.50"`
+}
+
+// TeamLaborReportRow is the labor time of one team member within one bucket.
+type TeamLaborReportRow struct {
+	Bucket    time.Time
+	UserID    uuid.UUID
+	User      string
+	LaborTime time.Duration
+}
+
+type TeamLaborReportResponse struct {
+	TeamID   uuid.UUID         `json:"team_id"`
+	GroupBy  string            `json:"group_by"`
+	TimeZone string            `json:"tz"`
+	Buckets  []TeamLaborBucket `json:"buckets"`
+}
+
+type TeamLaborBucket struct {
+	Start     string                `json:"start"`
+	LaborTime string                `json:"time"`
+	Users     []TeamLaborBucketUser `json:"users"`
+}
+
+type TeamLaborBucketUser struct {
+	ID        uuid.UUID `json:"id"`
+	User      string    `json:"user"`
+	LaborTime string    `json:"time"`
+}
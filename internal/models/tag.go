@@ -0,0 +1,36 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxTagLength = 64
+
+// Tag labels tasks. Names are unique within an organization.
+type Tag struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// TaskTag attaches a tag to a task or detaches it. Tags are created when
+// first attached.
+type TaskTag struct {
+	TaskID uuid.UUID `json:"task_id"`
+	Tag    string    `json:"tag"`
+}
+
+// NormalizeTag trims and lowercases a tag name, so that "Meeting" and
+// "meeting " are the same tag.
+func NormalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("%w: tag is empty", ErrInvalidTag)
+	}
+	if utf8.RuneCountInString(name) > maxTagLength {
+		return "", fmt.Errorf("%w: tag is longer than %d characters", ErrInvalidTag, maxTagLength)
+	}
+	return name, nil
+}
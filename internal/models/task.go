@@ -1,29 +1,86 @@
 package models
 
 import (
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"time"
 )
 
+const (
+	GroupByProject = "project"
+	GroupByClient  = "client"
+	GroupByTag     = "tag"
+)
+
 type UserTask struct {
-	UserID *uuid.UUID `json:"user_id"`
-	Text   *string    `json:"text"`
+	UserID    *uuid.UUID `json:"user_id"`
+	Text      *string    `json:"text"`
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
 }
 
 type Timer struct {
 	TaskID uuid.UUID `json:"task_id"`
 }
 
+// Task statuses. Timers only run on open tasks.
+const (
+	TaskOpen     = "open"
+	TaskDone     = "done"
+	TaskArchived = "archived"
+)
+
+func ValidTaskStatus(status string) bool {
+	return status == TaskOpen || status == TaskDone || status == TaskArchived
+}
+
 type Task struct {
-	ID     uuid.UUID `json:"id" `
-	Task   string    `json:"task"`
+	ID          uuid.UUID  `json:"id" `
+	Task        string     `json:"task"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Tags        []Tag      `json:"tags,omitempty"`
+	// HourlyRate overrides the rates of the project and the user.
+	HourlyRate *Rate `json:"hourly_rate,omitempty"`
+	Billable   bool  `json:"billable"`
+}
+
+// TaskFilter lists the tasks of a user, newest first.
+type TaskFilter struct {
+	UserID         uuid.UUID `json:"user_id"`
+	Status         *string   `json:"status,omitempty"`
+	Limit          uint64    `json:"limit,omitempty"`
+	Offset         uint64    `json:"offset,omitempty"`
+	OrganizationID uuid.UUID `json:"-"`
+}
+
+type TaskListResponse struct {
 	UserID uuid.UUID `json:"user_id"`
+	Tasks  []Task    `json:"tasks"`
+}
+
+// TaskUpdate renames a task or changes its status, rate or billing, nil
+// fields are kept. Completing or archiving a task stops its timer.
+type TaskUpdate struct {
+	ID         uuid.UUID `json:"id"`
+	Task       *string   `json:"task,omitempty"`
+	Status     *string   `json:"status,omitempty"`
+	HourlyRate *Rate     `json:"hourly_rate,omitempty"`
+	Billable   *bool     `json:"billable,omitempty"`
 }
 
 type LaborTimeRequest struct {
-	UserID    *uuid.UUID `json:"user_id"`
-	StartTime *time.Time `json:"limit,omitempty"`
-	EndTime   *time.Time `json:"offset,omitempty"`
+	UserID         *uuid.UUID `json:"user_id"`
+	StartTime      *time.Time `json:"limit,omitempty"`
+	EndTime        *time.Time `json:"offset,omitempty"`
+	ProjectID      *uuid.UUID `json:"project_id,omitempty"`
+	ClientID       *uuid.UUID `json:"client_id,omitempty"`
+	Tag            *string    `json:"tag,omitempty"`
+	GroupBy        string     `json:"group_by,omitempty"`
+	OrganizationID *uuid.UUID `json:"-"`
 }
 
 type LaborTimeResponse struct {
@@ -35,15 +92,75 @@ type TaskInfo struct {
 	ID        uuid.UUID      `json:"id"`
 	Task      *string        `json:"task"`
 	LaborTime *time.Duration `json:"time"`
+	ProjectID *uuid.UUID     `json:"project_id,omitempty"`
+	Project   *string        `json:"project,omitempty"`
+	ClientID  *uuid.UUID     `json:"client_id,omitempty"`
+	Client    *string        `json:"client,omitempty"`
+	Tags      []Tag          `json:"tags,omitempty"`
+	Billable  bool           `json:"billable"`
+	// Rate is the rate applying to the task, nil if none does.
+	Rate *Rate `json:"rate,omitempty"`
 }
 
 type GetTaskResponse struct {
 	UserID uuid.UUID     `json:"user_id"`
 	Tasks  []GetTaskInfo `json:"tasks"`
+	Groups []LaborGroup  `json:"groups,omitempty"`
+	// Earnings break the billable labor time down by rate.
+	Earnings []Earning `json:"earnings"`
 }
 
 type GetTaskInfo struct {
-	ID        uuid.UUID `json:"id"`
-	Task      string    `json:"task"`
-	LaborTime string    `json:"time"`
+	ID        uuid.UUID  `json:"id"`
+	Task      string     `json:"task"`
+	LaborTime string     `json:"time"`
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	Project   *string    `json:"project,omitempty"`
+	ClientID  *uuid.UUID `json:"client_id,omitempty"`
+	Client    *string    `json:"client,omitempty"`
+	Tags      []Tag      `json:"tags,omitempty"`
+	Billable  bool       `json:"billable"`
+	Rate      *Rate      `json:"rate,omitempty"`
+	// Amount is only set for billable tasks with a rate.
+	Amount *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"42.50"`
+}
+
+// LaborGroup is the labor time of all tasks sharing a project, a client or a
+// tag. ID and Name are empty for tasks without one. A task with several tags
+// counts towards each of them.
+type LaborGroup struct {
+	ID        *uuid.UUID `json:"id"`
+	Name      *string    `json:"name"`
+	LaborTime string     `json:"time"`
+}
+
+type Segment struct {
+	ID        uuid.UUID  `json:"id"`
+	Start     time.Time  `json:"start"`
+	Stop      *time.Time `json:"stop,omitempty"`
+	LaborTime string     `json:"time"`
+}
+
+type SegmentsResponse struct {
+	TaskID   uuid.UUID `json:"task_id"`
+	Segments []Segment `json:"segments"`
+}
+
+type TimeEntry struct {
+	ID     *uuid.UUID `json:"id,omitempty"`
+	TaskID *uuid.UUID `json:"task_id,omitempty"`
+	Start  *time.Time `json:"start,omitempty"`
+	Stop   *time.Time `json:"stop,omitempty"`
+}
+
+type DeleteTimeEntryRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// FormatLaborTime renders a duration the way labor time is shown to clients.
+func FormatLaborTime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := d / time.Hour
+	m := (d - h*time.Hour) / time.Minute
+	return fmt.Sprintf("hours: %d minutes: %d", h, m)
 }
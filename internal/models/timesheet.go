@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	TimesheetCSV  = "csv"
+	TimesheetXLSX = "xlsx"
+)
+
+type TimesheetRequest struct {
+	UserID         *uuid.UUID
+	OrganizationID *uuid.UUID
+	StartTime      *time.Time
+	EndTime        *time.Time
+	Format         string
+}
+
+// TimesheetRow is one finished labor_time segment.
+type TimesheetRow struct {
+	User  string
+	Task  *string
+	Start time.Time
+	Stop  time.Time
+}
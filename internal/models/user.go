@@ -1,11 +1,19 @@
 package models
 
 import (
+	"fmt"
 	"github.com/google/uuid"
+	"slices"
+	"strings"
+	"unicode"
 )
 
 type CreateUserRequest struct {
-	PassportNumber *string `json:"passportNumber,omitempty"`
+	PassportNumber *string    `json:"passportNumber,omitempty"`
+	Password       *string    `json:"password,omitempty"`
+	OrganizationID *uuid.UUID `json:"-"`
+	// Role defaults to employee.
+	Role *string `json:"-"`
 }
 
 type User struct {
@@ -15,19 +23,168 @@ type User struct {
 	Surname    *string    `json:"surname,omitempty"`
 	Patronymic *string    `json:"patronymic,omitempty"`
 	Address    *string    `json:"address,omitempty"`
+	Role       *string    `json:"role,omitempty"`
+	// OrganizationID is set on creation and scopes updates; users can't be
+	// moved between organizations.
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	TeamID         *uuid.UUID `json:"team_id,omitempty"`
+	// HourlyRate applies to the user's tasks without a task or project rate.
+	HourlyRate *Rate `json:"hourly_rate,omitempty"`
+	// PasswordHash is never sent to clients.
+	PasswordHash *string `json:"-"`
 }
 
 type FilterRequest struct {
-	Fields User   `json:"fields,omitempty"`
+	Fields User `json:"fields,omitempty"`
+	// Query searches surname, name, patronymic and address at once, typos
+	// included. Without Sort the best matches come first.
+	Query  string `json:"q,omitempty"`
 	Limit  uint64 `json:"limit,omitempty"`
 	Offset uint64 `json:"offset,omitempty"`
+	// Sort orders the users, ties are broken by ID so pages are stable.
+	Sort []SortField `json:"sort,omitempty"`
+	// Cursor continues the listing after the page it was returned with. It
+	// only fits the filter and sort of that page and excludes Offset.
+	Cursor       string `json:"cursor,omitempty"`
+	IncludeTotal bool   `json:"include_total,omitempty"`
 }
 
 type FilterResponse struct {
 	Users []User
+	// Total is the number of users matching the filter, it is only counted
+	// when IncludeTotal is set.
 	Total int64
+	// NextCursor fetches the next page, it is empty on the last one.
+	NextCursor string `json:",omitempty"`
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// UserSortFields are the fields users can be sorted by.
+var UserSortFields = []string{"surname", "name", "patronymic", "address", "role"}
+
+// ParseUserSort parses a comma separated list of fields, each optionally
+// prefixed with "-" for descending order, e.g. "surname,-name".
+func ParseUserSort(s string) ([]SortField, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var sort []SortField
+	seen := map[string]bool{}
+	for _, field := range strings.Split(s, ",") {
+		f := SortField{Field: strings.TrimSpace(field)}
+		if name, ok := strings.CutPrefix(f.Field, "-"); ok {
+			f.Field, f.Desc = name, true
+		}
+		if !slices.Contains(UserSortFields, f.Field) {
+			return nil, fmt.Errorf("%w: unknown field %q, expected one of %s", ErrInvalidSort, f.Field,
+				strings.Join(UserSortFields, ", "))
+		}
+		if seen[f.Field] {
+			return nil, fmt.Errorf("%w: field %q is given twice", ErrInvalidSort, f.Field)
+		}
+		seen[f.Field] = true
+		sort = append(sort, f)
+	}
+	return sort, nil
 }
 
 type DeleteUserRequest struct {
-	ID uuid.UUID `json:"id,omitempty"`
+	ID             uuid.UUID `json:"id,omitempty"`
+	OrganizationID uuid.UUID `json:"-"`
+}
+
+// MaskPassport hides all but the first and last two characters of a passport,
+// keeping separators, e.g. "1234 567856" becomes "12** ****56".
+func MaskPassport(passport string) string {
+	runes := []rune(passport)
+	for i, r := range runes {
+		if i >= 2 && i < len(runes)-2 && !unicode.IsSpace(r) {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
+
+// MaskPassport replaces the passport of the user with its masked form.
+func (u *User) MaskPassport() {
+	if u.Passport != nil {
+		masked := MaskPassport(*u.Passport)
+		u.Passport = &masked
+	}
+}
+
+// Redacted replaces personal data when users are printed.
+const Redacted = "[REDACTED]"
+
+// String keeps personal data out of logs: IDs and the role are printed as
+// they are, the other fields only show that they are set.
+func (u User) String() string {
+	var b strings.Builder
+	b.WriteString("{")
+	writeField(&b, "ID", u.ID)
+	writeField(&b, "Role", u.Role)
+	writeField(&b, "OrganizationID", u.OrganizationID)
+	writeField(&b, "TeamID", u.TeamID)
+	writeRedacted(&b, "Passport", u.Passport)
+	writeRedacted(&b, "Name", u.Name)
+	writeRedacted(&b, "Surname", u.Surname)
+	writeRedacted(&b, "Patronymic", u.Patronymic)
+	writeRedacted(&b, "Address", u.Address)
+	writeRedacted(&b, "PasswordHash", u.PasswordHash)
+	b.WriteString("}")
+	return b.String()
+}
+
+func (p CreateUserRequest) String() string {
+	var b strings.Builder
+	b.WriteString("{")
+	writeField(&b, "OrganizationID", p.OrganizationID)
+	writeField(&b, "Role", p.Role)
+	writeRedacted(&b, "PassportNumber", p.PassportNumber)
+	writeRedacted(&b, "Password", p.Password)
+	b.WriteString("}")
+	return b.String()
+}
+
+// String keeps the search query and the cursor, which holds the names of
+// the last user listed, out of logs.
+func (f FilterRequest) String() string {
+	var b strings.Builder
+	b.WriteString("{")
+	fmt.Fprintf(&b, "Fields:%v", f.Fields)
+	if f.Query != "" {
+		b.WriteString(" Query:" + Redacted)
+	}
+	fmt.Fprintf(&b, " Limit:%d Offset:%d", f.Limit, f.Offset)
+	if len(f.Sort) != 0 {
+		fmt.Fprintf(&b, " Sort:%v", f.Sort)
+	}
+	if f.Cursor != "" {
+		b.WriteString(" Cursor:" + Redacted)
+	}
+	fmt.Fprintf(&b, " IncludeTotal:%t}", f.IncludeTotal)
+	return b.String()
+}
+
+func writeField[T any](b *strings.Builder, name string, value *T) {
+	if value == nil {
+		return
+	}
+	if b.Len() > 1 {
+		b.WriteString(" ")
+	}
+	fmt.Fprintf(b, "%s:%v", name, *value)
+}
+
+func writeRedacted(b *strings.Builder, name string, value *string) {
+	if value == nil {
+		return
+	}
+	redacted := Redacted
+	writeField(b, name, &redacted)
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMaskPassport(t *testing.T) {
+	tests := []struct {
+		passport string
+		want     string
+	}{
+		{"1234 567856", "12** ****56"},
+		{"1234567856", "12******56"},
+		{"AB 123", "AB *23"},
+		{"1234", "1234"},
+		{"123", "123"},
+		{"", ""},
+		{"45 06 123456", "45 ** ****56"},
+		{"ПА123456", "ПА****56"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.passport, func(t *testing.T) {
+			if got := MaskPassport(tt.passport); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserSort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []SortField
+		ok   bool
+	}{
+		{"", nil, true},
+		{"surname", []SortField{{Field: "surname"}}, true},
+		{"surname,-name", []SortField{{Field: "surname"}, {Field: "name", Desc: true}}, true},
+		{" -role , address", []SortField{{Field: "role", Desc: true}, {Field: "address"}}, true},
+		{"passport", nil, false},
+		{"surname,-surname", nil, false},
+		{"surname,", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			got, err := ParseUserSort(tt.sort)
+			if tt.ok != (err == nil) {
+				t.Fatalf("got error %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidSort) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidSort)
+			}
+			if tt.ok && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Package policy decides which operations a caller may perform. Handlers
+// consult it before calling services; a nil error means the operation is
+// allowed, otherwise a *models.AccessError explains why it is not.
+package policy
+
+import (
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/google/uuid"
+	"strings"
+)
+
+func CreateUser(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin)
+}
+
+func DeleteUser(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin)
+}
+
+// ChangeUser lets users edit their own profile. Editing somebody else or
+// assigning a role, a team or an hourly rate is reserved for admins.
+func ChangeUser(caller models.Caller, user models.User) error {
+	if user.Role == nil && user.TeamID == nil && user.HourlyRate == nil && user.ID != nil && *user.ID == caller.ID {
+		return nil
+	}
+	return requireRole(caller, models.RoleAdmin)
+}
+
+// ViewUsers lets users look themselves up; listing others needs a manager.
+func ViewUsers(caller models.Caller, filter models.FilterRequest) error {
+	if filter.Fields.ID != nil && *filter.Fields.ID == caller.ID {
+		return nil
+	}
+	return requireRole(caller, models.RoleAdmin, models.RoleManager)
+}
+
+// ViewPassport decides whether passports are shown in full; everybody else
+// gets them masked.
+func ViewPassport(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin)
+}
+
+// CreateTask only lets users create tasks for themselves.
+func CreateTask(caller models.Caller, userID uuid.UUID) error {
+	if userID == caller.ID {
+		return nil
+	}
+	return &models.AccessError{Reason: models.ReasonNotOwner, Detail: "tasks can only be created for yourself"}
+}
+
+func ViewLaborTime(caller models.Caller, userID uuid.UUID) error {
+	if userID == caller.ID {
+		return nil
+	}
+	return requireRole(caller, models.RoleAdmin, models.RoleManager)
+}
+
+func ViewAllLaborTime(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin, models.RoleManager)
+}
+
+// ViewTeamLabor lets managers see totals of their own team and admins of any
+// team in their organization.
+func ViewTeamLabor(caller models.Caller, teamID uuid.UUID) error {
+	if caller.Role == models.RoleManager && caller.TeamID != nil && *caller.TeamID == teamID {
+		return nil
+	}
+	if caller.Role == models.RoleManager {
+		return &models.AccessError{Reason: models.ReasonNotTeamMember, Detail: "managers only see their own team"}
+	}
+	return requireRole(caller, models.RoleAdmin)
+}
+
+// CreateOrganization lets admins set up further organizations on the
+// deployment. They don't become members, the new organization gets its own
+// admin.
+func CreateOrganization(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin)
+}
+
+func ManageTeams(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin)
+}
+
+// ManageProjects covers changes to projects and clients.
+func ManageProjects(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin, models.RoleManager)
+}
+
+// ManageInvoices covers issuing and reading invoices of the organization.
+func ManageInvoices(caller models.Caller) error {
+	return requireRole(caller, models.RoleAdmin, models.RoleManager)
+}
+
+func requireRole(caller models.Caller, roles ...string) error {
+	for _, role := range roles {
+		if caller.Role == role {
+			return nil
+		}
+	}
+	return &models.AccessError{
+		Reason: models.ReasonRoleRequired,
+		Detail: "requires role " + strings.Join(roles, " or "),
+	}
+}
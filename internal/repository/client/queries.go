@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type ClientRepository struct {
+	conn    *sql.DB
+	timeout time.Duration
+	log     *logrus.Logger
+}
+
+func NewRepository(conn *sql.DB, timeout time.Duration, logger *logrus.Logger) *ClientRepository {
+	return &ClientRepository{
+		conn:    conn,
+		timeout: timeout,
+		log:     logger,
+	}
+}
+
+func (r *ClientRepository) Create(ctx context.Context, client models.Client) (models.Client, error) {
+	ctx, done := repository.StartQuery(ctx, "ClientRepository.Create", r.timeout)
+	defer done()
+
+	row := r.conn.QueryRowContext(ctx, "INSERT INTO clients (name, organization_id) values ($1, $2) RETURNING id",
+		client.Name, client.OrganizationID)
+	if err := row.Err(); err != nil {
+		return models.Client{}, models.ErrCreateClientResponse
+	}
+	var id uuid.UUID
+	err := row.Scan(&id)
+	if err != nil {
+		return models.Client{}, models.ErrCreateClientResponse
+	}
+	r.log.WithContext(ctx).Debugf("Inserted client: %v", id)
+	client.ID = &id
+	return client, nil
+}
+
+func (r *ClientRepository) Get(ctx context.Context, f models.ClientFilterRequest) (models.ClientFilterResponse, error) {
+	ctx, done := repository.StartQuery(ctx, "ClientRepository.Get", r.timeout)
+	defer done()
+
+	var clients []models.Client
+
+	builder := sq.Select("count(*) over ()", "id", "name", "organization_id").From("clients")
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	if f.Fields.ID != nil {
+		builder = builder.Where(sq.Eq{"id": f.Fields.ID})
+	}
+	if f.Fields.OrganizationID != nil {
+		builder = builder.Where(sq.Eq{"organization_id": f.Fields.OrganizationID})
+	}
+	if f.Fields.Name != nil {
+		builder = builder.Where(sq.ILike{"name": fmt.Sprintf("%%%v%%", *f.Fields.Name)})
+	}
+	if f.Limit != 0 {
+		builder = builder.Limit(f.Limit)
+	}
+	if f.Offset != 0 {
+		builder = builder.Offset(f.Offset)
+	}
+	query, args, err := builder.OrderBy("name").ToSql()
+	if err != nil {
+		return models.ClientFilterResponse{}, err
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.ClientFilterResponse{}, models.ErrGetClientResponse
+	}
+	defer rows.Close()
+
+	result := models.ClientFilterResponse{}
+	for rows.Next() {
+		client := models.Client{}
+		err = rows.Scan(&result.Total, &client.ID, &client.Name, &client.OrganizationID)
+		if err != nil {
+			return models.ClientFilterResponse{}, models.ErrGetClientResponse
+		}
+		clients = append(clients, client)
+	}
+	if err = rows.Err(); err != nil {
+		return models.ClientFilterResponse{}, errors.Join(models.ErrGetClientResponse, err)
+	}
+	result.Clients = clients
+	return result, nil
+}
+
+func (r *ClientRepository) Delete(ctx context.Context, request models.DeleteClientRequest) error {
+	ctx, done := repository.StartQuery(ctx, "ClientRepository.Delete", r.timeout)
+	defer done()
+
+	builder := sq.Delete("clients").Where(sq.Eq{"id": request.ID, "organization_id": request.OrganizationID})
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return models.ErrClientDeleteResponse
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	_, err = r.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.ErrClientDeleteResponse
+	}
+	return nil
+}
+
+func (r *ClientRepository) Set(ctx context.Context, client models.Client) error {
+	ctx, done := repository.StartQuery(ctx, "ClientRepository.Set", r.timeout)
+	defer done()
+
+	builder := sq.Update("clients").Where(sq.Eq{"id": client.ID, "organization_id": client.OrganizationID})
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	if client.Name != nil {
+		builder = builder.Set("name", client.Name)
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return models.ErrChangeClientInfoResponse
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	_, err = r.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.ErrChangeClientInfoResponse
+	}
+	return nil
+}
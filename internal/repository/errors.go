@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"errors"
+	"github.com/lib/pq"
+)
+
+// IsUniqueViolation reports whether err was raised by a unique index or
+// constraint.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
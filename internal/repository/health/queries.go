@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+type HealthRepository struct {
+	conn *sql.DB
+	// latest is the newest migration shipped with the service, 0 when it is
+	// unknown and the migration check is skipped.
+	latest int64
+	log    *logrus.Logger
+}
+
+func NewRepository(conn *sql.DB, latest int64, logger *logrus.Logger) *HealthRepository {
+	return &HealthRepository{
+		conn:   conn,
+		latest: latest,
+		log:    logger,
+	}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.conn.PingContext(ctx)
+}
+
+// Migrations checks that the last goose migration recorded in the database
+// is the latest one and was applied rather than rolled back.
+func (r *HealthRepository) Migrations(ctx context.Context) error {
+	if r.latest == 0 {
+		return nil
+	}
+
+	var version int64
+	var applied bool
+	err := r.conn.QueryRowContext(ctx, "SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC LIMIT 1").
+		Scan(&version, &applied)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrMigrationsBehind
+	}
+	if err != nil {
+		return err
+	}
+	if !applied || version != r.latest {
+		return models.ErrMigrationsBehind
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package invoice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type InvoiceRepository struct {
+	conn    *sql.DB
+	timeout time.Duration
+	log     *logrus.Logger
+}
+
+func NewRepository(conn *sql.DB, timeout time.Duration, logger *logrus.Logger) *InvoiceRepository {
+	return &InvoiceRepository{
+		conn:    conn,
+		timeout: timeout,
+		log:     logger,
+	}
+}
+
+// Create issues an invoice in one transaction: it selects the segments the
+// request covers, has bill turn them into an invoice, stores it and marks the
+// segments as invoiced. Selected segments stay locked until then and
+// invoices of an organization are issued one at a time, so no segment is
+// billed twice and numbers have no gaps.
+func (r *InvoiceRepository) Create(ctx context.Context, request models.InvoiceRequest,
+	bill func([]models.BillableSegment) (models.Invoice, error)) (models.Invoice, error) {
+	ctx, done := repository.StartQuery(ctx, "InvoiceRepository.Create", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+	}
+	defer tx.Rollback()
+
+	var seller string
+	err = tx.QueryRowContext(ctx, "SELECT name FROM organizations WHERE id = $1 FOR UPDATE",
+		request.OrganizationID).Scan(&seller)
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+	}
+
+	var client string
+	err = tx.QueryRowContext(ctx, "SELECT name FROM clients WHERE id = $1 AND organization_id = $2",
+		request.ClientID, request.OrganizationID).Scan(&client)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Invoice{}, models.ErrClientNotFound
+	}
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+	}
+
+	var currency *string
+	if request.Currency != "" {
+		currency = &request.Currency
+	}
+	segments, err := billableSegments(ctx, tx, request, currency)
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+	}
+
+	invoice, err := bill(segments)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	invoice.Seller = seller
+	invoice.ClientID = &request.ClientID
+	invoice.Client = client
+
+	err = tx.QueryRowContext(ctx, `insert into invoices (organization_id, number, seller, client_id, client, period_start,
+                      period_end, currency, tax_rate, subtotal, tax, total, created_by)
+select $1, coalesce(max(number), 0) + 1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+from invoices
+where organization_id = $1
+returning id, number, issued_at`,
+		request.OrganizationID, invoice.Seller, invoice.ClientID, invoice.Client, invoice.PeriodStart,
+		invoice.PeriodEnd, invoice.Currency, invoice.TaxRate, invoice.Subtotal, invoice.Tax, invoice.Total,
+		request.CreatedBy).Scan(&invoice.ID, &invoice.Number, &invoice.IssuedAt)
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+	}
+
+	for i, line := range invoice.Lines {
+		_, err = tx.ExecContext(ctx, "INSERT INTO invoice_lines (invoice_id, position, task_id, description, hours, "+
+			"unit_price, amount) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			invoice.ID, i+1, line.TaskID, line.Description, line.Hours, line.UnitPrice, line.Amount)
+		if err != nil {
+			return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+		}
+	}
+
+	var ids []string
+	for _, line := range invoice.Lines {
+		for _, id := range line.Segments {
+			ids = append(ids, id.String())
+		}
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE labor_time SET invoice_id = $1 WHERE id = any($2::uuid[])",
+		invoice.ID, pq.Array(ids))
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.Invoice{}, errors.Join(models.ErrCreateInvoice, err)
+	}
+	return invoice, nil
+}
+
+// billableSegments locks the segments covered by request. A segment
+// invoiced by a concurrent transaction is skipped once that one commits.
+func billableSegments(ctx context.Context, tx *sql.Tx, request models.InvoiceRequest,
+	currency *string) ([]models.BillableSegment, error) {
+	rows, err := tx.QueryContext(ctx, `select l.id,
+       t.id,
+       t.task,
+       l.start,
+       l.stop,
+       `+repository.RateAmount+`,
+       `+repository.RateCurrency+`
+from labor_time l
+         join tasks t on t.id = l.task_id
+         join users u on u.id = t.user_id
+         join projects p on p.id = t.project_id
+where p.client_id = $1
+  and u.organization_id = $2
+  and l.stop is not null
+  and l.invoice_id is null
+  and t.billable
+  and `+repository.RateAmount+` is not null
+  and ($3::timestamptz is null or l.start at time zone 'utc' >= $3)
+  and ($4::timestamptz is null or l.stop at time zone 'utc' <= $4)
+  and ($5::varchar is null or `+repository.RateCurrency+` = $5)
+order by l.start
+for update of l`,
+		request.ClientID, request.OrganizationID, request.StartTime, request.EndTime, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segments []models.BillableSegment
+	for rows.Next() {
+		s := models.BillableSegment{}
+		err = rows.Scan(&s.ID, &s.TaskID, &s.Task, &s.Start, &s.Stop, &s.Rate.Amount, &s.Rate.Currency)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+func (r *InvoiceRepository) Get(ctx context.Context, id, organizationID uuid.UUID) (models.Invoice, error) {
+	ctx, done := repository.StartQuery(ctx, "InvoiceRepository.Get", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	invoice := models.Invoice{}
+	err := r.conn.QueryRowContext(ctx, `select id, number, seller, client_id, client, issued_at, period_start, period_end,
+       currency, tax_rate, subtotal, tax, total
+from invoices
+where id = $1
+  and organization_id = $2`, id, organizationID).Scan(&invoice.ID, &invoice.Number, &invoice.Seller,
+		&invoice.ClientID, &invoice.Client, &invoice.IssuedAt, &invoice.PeriodStart, &invoice.PeriodEnd,
+		&invoice.Currency, &invoice.TaxRate, &invoice.Subtotal, &invoice.Tax, &invoice.Total)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Invoice{}, models.ErrInvoiceNotFound
+	}
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrGetInvoice, err)
+	}
+
+	rows, err := r.conn.QueryContext(ctx, "SELECT task_id, description, hours, unit_price, amount FROM invoice_lines "+
+		"WHERE invoice_id = $1 ORDER BY position", id)
+	if err != nil {
+		return models.Invoice{}, errors.Join(models.ErrGetInvoice, err)
+	}
+	defer rows.Close()
+
+	invoice.Lines = []models.InvoiceLine{}
+	for rows.Next() {
+		line := models.InvoiceLine{}
+		err = rows.Scan(&line.TaskID, &line.Description, &line.Hours, &line.UnitPrice, &line.Amount)
+		if err != nil {
+			return models.Invoice{}, errors.Join(models.ErrGetInvoice, err)
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		return models.Invoice{}, errors.Join(models.ErrGetInvoice, err)
+	}
+	return invoice, nil
+}
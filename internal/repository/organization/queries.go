@@ -0,0 +1,54 @@
+package organization
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type OrganizationRepository struct {
+	conn    *sql.DB
+	timeout time.Duration
+	log     *logrus.Logger
+}
+
+func NewRepository(conn *sql.DB, timeout time.Duration, logger *logrus.Logger) *OrganizationRepository {
+	return &OrganizationRepository{
+		conn:    conn,
+		timeout: timeout,
+		log:     logger,
+	}
+}
+
+func (r *OrganizationRepository) Create(ctx context.Context, organization models.Organization) (models.Organization, error) {
+	ctx, done := repository.StartQuery(ctx, "OrganizationRepository.Create", r.timeout)
+	defer done()
+
+	var id uuid.UUID
+	err := r.conn.QueryRowContext(ctx, "INSERT INTO organizations (name) values ($1) RETURNING id",
+		organization.Name).Scan(&id)
+	if err != nil {
+		return models.Organization{}, errors.Join(models.ErrCreateOrganization, err)
+	}
+	r.log.WithContext(ctx).Debugf("Inserted organization: %v", id)
+	organization.ID = &id
+	return organization, nil
+}
+
+// Delete removes the organization with everything that belongs to it.
+func (r *OrganizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, done := repository.StartQuery(ctx, "OrganizationRepository.Delete", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing delete organization: %v", id)
+	_, err := r.conn.ExecContext(ctx, "DELETE FROM organizations WHERE id = $1", id)
+	if err != nil {
+		return errors.Join(models.ErrCreateOrganization, err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type ProjectRepository struct {
+	conn    *sql.DB
+	timeout time.Duration
+	log     *logrus.Logger
+}
+
+func NewRepository(conn *sql.DB, timeout time.Duration, logger *logrus.Logger) *ProjectRepository {
+	return &ProjectRepository{
+		conn:    conn,
+		timeout: timeout,
+		log:     logger,
+	}
+}
+
+func (r *ProjectRepository) Create(ctx context.Context, project models.Project) (models.Project, error) {
+	ctx, done := repository.StartQuery(ctx, "ProjectRepository.Create", r.timeout)
+	defer done()
+
+	var amount, currency interface{}
+	if project.HourlyRate != nil {
+		amount, currency = repository.RateValues(*project.HourlyRate)
+	}
+
+	row := r.conn.QueryRowContext(ctx, "INSERT INTO projects (name, client_id, organization_id, hourly_rate, rate_currency) "+
+		"SELECT $1, $2, $3, $4::numeric, $5::char(3) "+
+		"WHERE $2::uuid IS NULL OR EXISTS (SELECT 1 FROM clients WHERE id = $2 AND organization_id = $3) RETURNING id",
+		project.Name, project.ClientID, project.OrganizationID, amount, currency)
+	if err := row.Err(); err != nil {
+		return models.Project{}, models.ErrCreateProjectResponse
+	}
+	var id uuid.UUID
+	err := row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Project{}, models.ErrClientNotFound
+	}
+	if err != nil {
+		return models.Project{}, models.ErrCreateProjectResponse
+	}
+	r.log.WithContext(ctx).Debugf("Inserted project: %v", id)
+	project.ID = &id
+	if project.HourlyRate != nil && project.HourlyRate.IsZero() {
+		project.HourlyRate = nil
+	}
+	return project, nil
+}
+
+func (r *ProjectRepository) Get(ctx context.Context, f models.ProjectFilterRequest) (models.ProjectFilterResponse, error) {
+	ctx, done := repository.StartQuery(ctx, "ProjectRepository.Get", r.timeout)
+	defer done()
+
+	var projects []models.Project
+
+	builder := sq.Select("count(*) over ()", "id", "name", "client_id", "organization_id", "hourly_rate",
+		"rate_currency").From("projects")
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	if f.Fields.ID != nil {
+		builder = builder.Where(sq.Eq{"id": f.Fields.ID})
+	}
+	if f.Fields.OrganizationID != nil {
+		builder = builder.Where(sq.Eq{"organization_id": f.Fields.OrganizationID})
+	}
+	if f.Fields.Name != nil {
+		builder = builder.Where(sq.ILike{"name": fmt.Sprintf("%%%v%%", *f.Fields.Name)})
+	}
+	if f.Fields.ClientID != nil {
+		builder = builder.Where(sq.Eq{"client_id": f.Fields.ClientID})
+	}
+	if f.Limit != 0 {
+		builder = builder.Limit(f.Limit)
+	}
+	if f.Offset != 0 {
+		builder = builder.Offset(f.Offset)
+	}
+	query, args, err := builder.OrderBy("name").ToSql()
+	if err != nil {
+		return models.ProjectFilterResponse{}, err
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.ProjectFilterResponse{}, models.ErrGetProjectResponse
+	}
+	defer rows.Close()
+
+	result := models.ProjectFilterResponse{}
+	for rows.Next() {
+		project := models.Project{}
+		rate := repository.NullRate{}
+		err = rows.Scan(&result.Total, &project.ID, &project.Name, &project.ClientID, &project.OrganizationID,
+			&rate.Amount, &rate.Currency)
+		if err != nil {
+			return models.ProjectFilterResponse{}, models.ErrGetProjectResponse
+		}
+		project.HourlyRate = rate.Rate()
+		projects = append(projects, project)
+	}
+	if err = rows.Err(); err != nil {
+		return models.ProjectFilterResponse{}, errors.Join(models.ErrGetProjectResponse, err)
+	}
+	result.Projects = projects
+	return result, nil
+}
+
+func (r *ProjectRepository) Delete(ctx context.Context, request models.DeleteProjectRequest) error {
+	ctx, done := repository.StartQuery(ctx, "ProjectRepository.Delete", r.timeout)
+	defer done()
+
+	builder := sq.Delete("projects").Where(sq.Eq{"id": request.ID, "organization_id": request.OrganizationID})
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return models.ErrProjectDeleteResponse
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	_, err = r.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.ErrProjectDeleteResponse
+	}
+	return nil
+}
+
+func (r *ProjectRepository) Set(ctx context.Context, project models.Project) error {
+	ctx, done := repository.StartQuery(ctx, "ProjectRepository.Set", r.timeout)
+	defer done()
+
+	builder := sq.Update("projects").Where(sq.Eq{"id": project.ID, "organization_id": project.OrganizationID})
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	if project.Name != nil {
+		builder = builder.Set("name", project.Name)
+	}
+	if project.HourlyRate != nil {
+		amount, currency := repository.RateValues(*project.HourlyRate)
+		builder = builder.Set("hourly_rate", amount).Set("rate_currency", currency)
+	}
+	if project.ClientID != nil {
+		builder = builder.Set("client_id", project.ClientID).
+			Where("exists (select 1 from clients where id = ? and organization_id = ?)", project.ClientID,
+				project.OrganizationID)
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return models.ErrChangeProjectInfoResponse
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	_, err = r.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.ErrChangeProjectInfoResponse
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"time"
+)
+
+// StartQuery starts the span of a repository method and bounds its queries by
+// timeout, zero leaves them unbounded. The returned function ends both and
+// must be deferred.
+func StartQuery(ctx context.Context, name string, timeout time.Duration) (context.Context, func()) {
+	ctx, span := tracing.StartQuery(ctx, name)
+	if timeout <= 0 {
+		return ctx, func() { span.End() }
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		span.End()
+	}
+}
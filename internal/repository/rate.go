@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// RateAmount and RateCurrency select the hourly rate applying to the task
+// aliased t, owned by the user aliased u, within the project aliased p: the
+// task's, then the project's, then the user's.
+const (
+	RateAmount   = "coalesce(t.hourly_rate, p.hourly_rate, u.hourly_rate)"
+	RateCurrency = "case when t.hourly_rate is not null then t.rate_currency " +
+		"when p.hourly_rate is not null then p.rate_currency else u.rate_currency end"
+)
+
+// RateColumns selects the applying rate as rate and currency.
+const RateColumns = RateAmount + " as rate, " + RateCurrency + " as currency"
+
+// NullRate scans the nullable hourly_rate and rate_currency columns.
+type NullRate struct {
+	Amount   decimal.NullDecimal
+	Currency sql.NullString
+}
+
+// Rate returns nil when no rate is set.
+func (n NullRate) Rate() *models.Rate {
+	if !n.Amount.Valid || !n.Currency.Valid {
+		return nil
+	}
+	return &models.Rate{Amount: n.Amount.Decimal, Currency: n.Currency.String}
+}
+
+// RateValues returns the hourly_rate and rate_currency to store for rate, a
+// zero rate is stored as NULLs.
+func RateValues(rate models.Rate) (interface{}, interface{}) {
+	if rate.IsZero() {
+		return nil, nil
+	}
+	return rate.Amount, rate.Currency
+}
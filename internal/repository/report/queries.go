@@ -0,0 +1,200 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type ReportRepository struct {
+	conn    *sql.DB
+	timeout time.Duration
+	log     *logrus.Logger
+}
+
+func NewRepository(conn *sql.DB, timeout time.Duration, logger *logrus.Logger) *ReportRepository {
+	return &ReportRepository{
+		conn:    conn,
+		timeout: timeout,
+		log:     logger,
+	}
+}
+
+// Labor buckets labor time by request.GroupBy in request.TimeZone. Segments
+// are clipped to the requested window and split at bucket boundaries, so an
+// interval crossing midnight is shared between both days. Running timers are
+// counted up to now.
+func (r *ReportRepository) Labor(ctx context.Context, request models.LaborReportRequest) ([]models.LaborReportRow, error) {
+	ctx, done := repository.StartQuery(ctx, "ReportRepository.Labor", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	rows, err := r.conn.QueryContext(ctx, `with segments as (select t.id                                                             as task_id,
+                         t.task,
+                         t.billable,
+                         `+repository.RateColumns+`,
+                         greatest(l.start at time zone 'utc', coalesce($4::timestamptz, '-infinity'))   as start,
+                         least(coalesce(l.stop at time zone 'utc', now()),
+                               coalesce($5::timestamptz, 'infinity'))                                   as stop
+                  from tasks t
+                           join labor_time l on t.id = l.task_id
+                           join users u on u.id = t.user_id
+                           left join projects p on p.id = t.project_id
+                  where t.user_id = $1
+                    and ($6::uuid is null or u.organization_id = $6)),
+     buckets as (select s.task_id,
+                        s.task,
+                        s.billable,
+                        s.rate,
+                        s.currency,
+                        b.bucket,
+                        least(s.stop, (b.bucket + ('1 ' || $3)::interval) at time zone $2)
+                            - greatest(s.start, b.bucket at time zone $2) as delta
+                 from segments s,
+                      generate_series(date_trunc($3, s.start at time zone $2),
+                                      s.stop at time zone $2,
+                                      ('1 ' || $3)::interval) as b(bucket)
+                 where s.stop > s.start)
+select bucket,
+       task_id,
+       task,
+       extract(epoch from sum(delta))::bigint as delta,
+       billable,
+       rate,
+       currency
+from buckets
+where delta > interval '0'
+group by bucket, task_id, task, billable, rate, currency
+order by bucket, task`,
+		request.UserID, request.TimeZone, request.GroupBy, request.StartTime, request.EndTime, request.OrganizationID)
+	if err != nil {
+		return nil, errors.Join(models.ErrGetLaborReport, err)
+	}
+	defer rows.Close()
+
+	var result []models.LaborReportRow
+	for rows.Next() {
+		row := models.LaborReportRow{}
+
+		var seconds int64
+		rate := repository.NullRate{}
+		err = rows.Scan(&row.Bucket, &row.TaskID, &row.Task, &seconds, &row.Billable, &rate.Amount, &rate.Currency)
+		if err != nil {
+			return nil, errors.Join(models.ErrGetLaborReport, err)
+		}
+		row.Rate = rate.Rate()
+		row.LaborTime = time.Duration(seconds) * time.Second
+		result = append(result, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Join(models.ErrGetLaborReport, err)
+	}
+	return result, nil
+}
+
+// TeamLabor buckets labor time of every member of request.TeamID the same way
+// as Labor, broken down by user instead of task.
+func (r *ReportRepository) TeamLabor(ctx context.Context, request models.LaborReportRequest) ([]models.TeamLaborReportRow, error) {
+	ctx, done := repository.StartQuery(ctx, "ReportRepository.TeamLabor", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	rows, err := r.conn.QueryContext(ctx, `with segments as (select u.id                                                             as user_id,
+                         concat_ws(' ', u.surname, u.name, u.patronymic)                  as name,
+                         greatest(l.start at time zone 'utc', coalesce($4::timestamptz, '-infinity'))   as start,
+                         least(coalesce(l.stop at time zone 'utc', now()),
+                               coalesce($5::timestamptz, 'infinity'))                                   as stop
+                  from users u
+                           join tasks t on t.user_id = u.id
+                           join labor_time l on t.id = l.task_id
+                  where u.team_id = $1
+                    and ($6::uuid is null or u.organization_id = $6)),
+     buckets as (select s.user_id,
+                        s.name,
+                        b.bucket,
+                        least(s.stop, (b.bucket + ('1 ' || $3)::interval) at time zone $2)
+                            - greatest(s.start, b.bucket at time zone $2) as delta
+                 from segments s,
+                      generate_series(date_trunc($3, s.start at time zone $2),
+                                      s.stop at time zone $2,
+                                      ('1 ' || $3)::interval) as b(bucket)
+                 where s.stop > s.start)
+select bucket,
+       user_id,
+       name,
+       extract(epoch from sum(delta))::bigint as delta
+from buckets
+where delta > interval '0'
+group by bucket, user_id, name
+order by bucket, name`,
+		request.TeamID, request.TimeZone, request.GroupBy, request.StartTime, request.EndTime, request.OrganizationID)
+	if err != nil {
+		return nil, errors.Join(models.ErrGetLaborReport, err)
+	}
+	defer rows.Close()
+
+	var result []models.TeamLaborReportRow
+	for rows.Next() {
+		row := models.TeamLaborReportRow{}
+
+		var seconds int64
+		err = rows.Scan(&row.Bucket, &row.UserID, &row.User, &seconds)
+		if err != nil {
+			return nil, errors.Join(models.ErrGetLaborReport, err)
+		}
+		row.LaborTime = time.Duration(seconds) * time.Second
+		result = append(result, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Join(models.ErrGetLaborReport, err)
+	}
+	return result, nil
+}
+
+// Timesheet calls fn for every finished segment in the window, ordered by user
+// and start, reading rows one by one so large ranges are never held in memory.
+func (r *ReportRepository) Timesheet(ctx context.Context, request models.TimesheetRequest, fn func(models.TimesheetRow) error) error {
+	// Exports stream for as long as the client reads, so only the request
+	// context bounds them.
+	ctx, done := repository.StartQuery(ctx, "ReportRepository.Timesheet", 0)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	rows, err := r.conn.QueryContext(ctx, `select concat_ws(' ', u.surname, u.name, u.patronymic),
+       t.task,
+       l.start,
+       l.stop
+from labor_time l
+         join tasks t on t.id = l.task_id
+         join users u on u.id = t.user_id
+where l.stop is not null
+  and ($1::uuid is null or u.id = $1)
+  and ($2::timestamptz is null or l.stop at time zone 'utc' > $2)
+  and ($3::timestamptz is null or l.start at time zone 'utc' < $3)
+  and ($4::uuid is null or u.organization_id = $4)
+order by u.surname, u.name, u.id, l.start`,
+		request.UserID, request.StartTime, request.EndTime, request.OrganizationID)
+	if err != nil {
+		return errors.Join(models.ErrGetTimesheet, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := models.TimesheetRow{}
+		err = rows.Scan(&row.User, &row.Task, &row.Start, &row.Stop)
+		if err != nil {
+			return errors.Join(models.ErrGetTimesheet, err)
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return errors.Join(models.ErrGetTimesheet, err)
+	}
+	return nil
+}
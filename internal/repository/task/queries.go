@@ -1,109 +1,714 @@
 package task
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"time"
 )
 
 type TaskRepository struct {
-	conn *sql.DB
-	log  *logrus.Logger
+	conn    *sql.DB
+	timeout time.Duration
+	log     *logrus.Logger
 }
 
-func NewRepository(conn *sql.DB, logger *logrus.Logger) *TaskRepository {
+func NewRepository(conn *sql.DB, timeout time.Duration, logger *logrus.Logger) *TaskRepository {
 	return &TaskRepository{
-		conn: conn,
-		log:  logger,
+		conn:    conn,
+		timeout: timeout,
+		log:     logger,
 	}
 }
 
-func (r *TaskRepository) Create(task models.Task) (models.Task, error) {
-	r.log.Debugf("Executing insert task: %+v", task)
-	row := r.conn.QueryRow("INSERT INTO tasks (task, user_id) VALUES "+
-		"($1, $2) RETURNING id", task.Task, task.UserID)
+func (r *TaskRepository) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Create", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing insert task: %+v", task)
+	row := r.conn.QueryRowContext(ctx, `INSERT INTO tasks (task, user_id, project_id)
+SELECT $1, $2, $3
+WHERE $3::uuid IS NULL
+   OR EXISTS (SELECT 1
+              FROM projects p
+                       JOIN users u ON u.organization_id = p.organization_id
+              WHERE p.id = $3
+                AND u.id = $2)
+RETURNING id, status, created_at, billable`, task.Task, task.UserID, task.ProjectID)
 	if err := row.Err(); err != nil {
 		return models.Task{}, models.ErrCreateTaskResponse
 	}
 
-	var id uuid.UUID
-	err := row.Scan(&id)
+	err := row.Scan(&task.ID, &task.Status, &task.CreatedAt, &task.Billable)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Task{}, models.ErrProjectNotFound
+	}
 	if err != nil {
 		return models.Task{}, models.ErrCreateTaskResponse
 	}
-	task.ID = id
 	return task, nil
 }
 
-func (r *TaskRepository) Get(request models.LaborTimeRequest) (models.LaborTimeResponse, error) {
-	r.log.Debugf("Executing query")
-	rows, err := r.conn.Query(`select t.id,
+// tagsColumn selects the tags of the task aliased t as a JSON array.
+const tagsColumn = `(select coalesce(json_agg(json_build_object('id', g.id, 'name', g.name) order by g.name), '[]')
+        from task_tags tt
+                 join tags g on g.id = tt.tag_id
+        where tt.task_id = t.id)`
+
+const taskColumns = "t.id, t.task, t.user_id, t.project_id, t.status, t.created_at, t.completed_at, " +
+	"t.hourly_rate, t.rate_currency, t.billable, " + tagsColumn
+
+func scanTask(row interface{ Scan(...interface{}) error }) (models.Task, error) {
+	task := models.Task{}
+	rate := repository.NullRate{}
+	var tags []byte
+	err := row.Scan(&task.ID, &task.Task, &task.UserID, &task.ProjectID, &task.Status, &task.CreatedAt, &task.CompletedAt,
+		&rate.Amount, &rate.Currency, &task.Billable, &tags)
+	if err != nil {
+		return models.Task{}, err
+	}
+	task.HourlyRate = rate.Rate()
+	task.Tags, err = decodeTags(tags)
+	return task, err
+}
+
+func decodeTags(data []byte) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// List returns the tasks of a user whether or not they have labor time,
+// newest first.
+func (r *TaskRepository) List(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.List", r.timeout)
+	defer done()
+
+	// NULL lifts the limit.
+	var limit *uint64
+	if filter.Limit != 0 {
+		limit = &filter.Limit
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	rows, err := r.conn.QueryContext(ctx, `select `+taskColumns+`
+from tasks t
+         join users u on u.id = t.user_id
+where t.user_id = $1
+  and u.organization_id = $2
+  and ($3::varchar is null or t.status = $3)
+order by t.created_at desc, t.id
+limit $4 offset $5`,
+		filter.UserID, filter.OrganizationID, filter.Status, limit, filter.Offset)
+	if err != nil {
+		return nil, errors.Join(models.ErrGetTaskResponse, err)
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, errors.Join(models.ErrGetTaskResponse, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Join(models.ErrGetTaskResponse, err)
+	}
+	return tasks, nil
+}
+
+func (r *TaskRepository) GetTask(ctx context.Context, taskID uuid.UUID) (models.Task, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.GetTask", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	row := r.conn.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks t WHERE t.id = $1", taskID)
+	if err := row.Err(); err != nil {
+		return models.Task{}, errors.Join(models.ErrGetTaskResponse, err)
+	}
+
+	task, err := scanTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	if err != nil {
+		return models.Task{}, errors.Join(models.ErrGetTaskResponse, err)
+	}
+	return task, nil
+}
+
+// Update renames the task and changes its status, rate and billing.
+// Completing a task stamps completed_at and reopening clears it; completing
+// or archiving stops the timer of the task in the same transaction.
+func (r *TaskRepository) Update(ctx context.Context, update models.TaskUpdate) (models.Task, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Update", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing update task: %v", update.ID)
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Task{}, errors.Join(models.ErrUpdateTask, err)
+	}
+	defer tx.Rollback()
+
+	var amount, currency interface{}
+	if update.HourlyRate != nil {
+		amount, currency = repository.RateValues(*update.HourlyRate)
+	}
+
+	row := tx.QueryRowContext(ctx, `update tasks t
+set task          = coalesce($2::text, t.task),
+    status        = coalesce($3::varchar, t.status),
+    completed_at  = case
+                        when $3::varchar is null or $3::varchar = t.status then t.completed_at
+                        when $3::varchar = 'done' then now() at time zone 'utc'
+                        when $3::varchar = 'open' then null
+                        else t.completed_at end,
+    hourly_rate   = case when $4 then $5::numeric else t.hourly_rate end,
+    rate_currency = case when $4 then $6::char(3) else t.rate_currency end,
+    billable      = coalesce($7::boolean, t.billable)
+where t.id = $1
+returning `+taskColumns, update.ID, update.Task, update.Status, update.HourlyRate != nil, amount, currency, update.Billable)
+	task, err := scanTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Task{}, models.ErrTaskNotFound
+	}
+	if err != nil {
+		return models.Task{}, errors.Join(models.ErrUpdateTask, err)
+	}
+
+	if task.Status != models.TaskOpen {
+		_, err = tx.ExecContext(ctx, "update labor_time set stop = coalesce(stop, now() at time zone 'utc'), paused = false "+
+			"WHERE task_id = $1 and (stop is null or paused)", task.ID)
+		if err != nil {
+			return models.Task{}, errors.Join(models.ErrUpdateTask, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.Task{}, errors.Join(models.ErrUpdateTask, err)
+	}
+	return task, nil
+}
+
+func (r *TaskRepository) Get(ctx context.Context, request models.LaborTimeRequest) (models.LaborTimeResponse, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Get", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	rows, err := r.conn.QueryContext(ctx, `select t.id,
        t.task,
-       extract(epoch from (sum(l.stop - l.start)))::int as delta
+       extract(epoch from (sum(l.stop - l.start)))::int as delta,
+       p.id,
+       p.name,
+       c.id,
+       c.name,
+       `+tagsColumn+`,
+       t.billable,
+       `+repository.RateColumns+`
 from tasks t
          join public.labor_time l on t.id = l.task_id
+         join users u on u.id = t.user_id
+         left join projects p on p.id = t.project_id
+         left join clients c on c.id = p.client_id
 where t.user_id = $3
   and l.start between $2
     and $1
   and l.stop <= $1
-group by t.id
+  and ($4::uuid is null or p.id = $4)
+  and ($5::uuid is null or c.id = $5)
+  and ($6::uuid is null or u.organization_id = $6)
+  and ($7::varchar is null or exists(select 1
+                                     from task_tags tt
+                                              join tags g on g.id = tt.tag_id
+                                     where tt.task_id = t.id
+                                       and g.name = $7))
+group by t.id, p.id, c.id, u.id
 order by delta`,
-		request.EndTime, request.StartTime, request.UserID)
+		request.EndTime, request.StartTime, request.UserID, request.ProjectID, request.ClientID, request.OrganizationID,
+		request.Tag)
 	if err != nil {
 		return models.LaborTimeResponse{}, errors.Join(models.ErrGetTaskResponse, err)
 	}
+	defer rows.Close()
 
 	resp := models.LaborTimeResponse{UserID: *request.UserID}
 	for rows.Next() {
 		task := models.TaskInfo{}
 
-		var duration time.Duration
-		err = rows.Scan(&task.ID, &task.Task, &duration)
+		var seconds int64
+		var tags []byte
+		rate := repository.NullRate{}
+		err = rows.Scan(&task.ID, &task.Task, &seconds, &task.ProjectID, &task.Project, &task.ClientID, &task.Client, &tags,
+			&task.Billable, &rate.Amount, &rate.Currency)
 		if err != nil {
 			return models.LaborTimeResponse{}, errors.Join(models.ErrGetTaskResponse, err)
 		}
+		task.Rate = rate.Rate()
+		task.Tags, err = decodeTags(tags)
+		if err != nil {
+			return models.LaborTimeResponse{}, errors.Join(models.ErrGetTaskResponse, err)
+		}
+		duration := time.Duration(seconds) * time.Second
 		task.LaborTime = &duration
 		resp.Tasks = append(resp.Tasks, task)
 	}
+	if err = rows.Err(); err != nil {
+		return models.LaborTimeResponse{}, errors.Join(models.ErrGetTaskResponse, err)
+	}
 	return resp, nil
 }
 
-func (r *TaskRepository) Start(taskID uuid.UUID) error {
-	r.log.Debugf("Executing query")
-	_, err := r.conn.Exec("INSERT INTO labor_time (task_id) VALUES ($1)", taskID)
+func (r *TaskRepository) Start(ctx context.Context, taskID uuid.UUID) error {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Start", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ErrStartTimer
+	}
+	defer tx.Rollback()
+
+	err = checkOverlap(ctx, tx, models.TimeEntry{TaskID: &taskID})
+	if errors.Is(err, models.ErrTimeEntryOverlap) {
+		return err
+	}
 	if err != nil {
+		return errors.Join(models.ErrStartTimer, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "update labor_time set paused = false WHERE task_id = $1 and paused", taskID)
+	if err != nil {
+		return models.ErrStartTimer
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO labor_time (task_id) VALUES ($1)", taskID)
+	if err != nil {
+		return models.ErrStartTimer
+	}
+
+	if err = tx.Commit(); err != nil {
 		return models.ErrStartTimer
 	}
 	return nil
 }
 
-func (r *TaskRepository) Stop(taskID uuid.UUID) error {
-	r.log.Debugf("Executing query")
-	_, err := r.conn.Exec("update labor_time set stop = now() WHERE task_id = $1 and stop is null", taskID)
+func (r *TaskRepository) Stop(ctx context.Context, taskID uuid.UUID) error {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Stop", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	res, err := r.conn.ExecContext(ctx, "update labor_time set stop = coalesce(stop, now() at time zone 'utc'), paused = false "+
+		"WHERE task_id = $1 and (stop is null or paused)", taskID)
+	if err != nil {
+		return models.ErrStopTimer
+	}
+
+	n, err := res.RowsAffected()
 	if err != nil {
 		return models.ErrStopTimer
 	}
+	if n == 0 {
+		return models.ErrTimerNotRunning
+	}
 	return nil
 }
 
-func (r *TaskRepository) IsStarted(taskID uuid.UUID) (bool, error) {
-	r.log.Debugf("Executing query")
-	row := r.conn.QueryRow("SELECT id FROM labor_time WHERE task_id = $1 and stop is null", taskID)
+func (r *TaskRepository) Pause(ctx context.Context, taskID uuid.UUID) error {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Pause", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	res, err := r.conn.ExecContext(ctx, "update labor_time set stop = now() at time zone 'utc', paused = true "+
+		"WHERE task_id = $1 and stop is null", taskID)
+	if err != nil {
+		return models.ErrPauseTimer
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.ErrPauseTimer
+	}
+	if n == 0 {
+		return models.ErrTimerNotStarted
+	}
+	return nil
+}
+
+func (r *TaskRepository) Resume(ctx context.Context, taskID uuid.UUID) error {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Resume", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ErrResumeTimer
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "update labor_time set paused = false WHERE task_id = $1 and paused", taskID)
+	if err != nil {
+		return models.ErrResumeTimer
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.ErrResumeTimer
+	}
+	if n == 0 {
+		return models.ErrTimerNotPaused
+	}
+
+	err = checkOverlap(ctx, tx, models.TimeEntry{TaskID: &taskID})
+	if errors.Is(err, models.ErrTimeEntryOverlap) {
+		return err
+	}
+	if err != nil {
+		return errors.Join(models.ErrResumeTimer, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO labor_time (task_id) VALUES ($1)", taskID)
+	if err != nil {
+		return models.ErrResumeTimer
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.ErrResumeTimer
+	}
+	return nil
+}
+
+func (r *TaskRepository) Segments(ctx context.Context, taskID uuid.UUID) ([]models.Segment, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Segments", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	rows, err := r.conn.QueryContext(ctx, "SELECT id, start, stop FROM labor_time WHERE task_id = $1 order by start", taskID)
+	if err != nil {
+		return nil, errors.Join(models.ErrGetSegments, err)
+	}
+	defer rows.Close()
+
+	var segments []models.Segment
+	for rows.Next() {
+		segment := models.Segment{}
+		err = rows.Scan(&segment.ID, &segment.Start, &segment.Stop)
+		if err != nil {
+			return nil, errors.Join(models.ErrGetSegments, err)
+		}
+		segments = append(segments, segment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Join(models.ErrGetSegments, err)
+	}
+	return segments, nil
+}
+
+func (r *TaskRepository) IsStarted(ctx context.Context, taskID uuid.UUID) (bool, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.IsStarted", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	row := r.conn.QueryRowContext(ctx, "SELECT id FROM labor_time WHERE task_id = $1 and stop is null", taskID)
 	if err := row.Err(); err != nil {
 		return false, models.ErrCheckTimerStatus
 	}
 
 	var id uuid.UUID
 	err := row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
 	if err != nil {
 		return false, models.ErrCheckTimerStatus
 	}
+	return true, nil
+}
 
-	if id == uuid.Nil {
-		return false, nil
+// CreateEntry stores the entry unless it overlaps other labor time of the
+// task owner.
+func (r *TaskRepository) CreateEntry(ctx context.Context, entry models.TimeEntry) (models.TimeEntry, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.CreateEntry", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing insert time entry for task: %v", entry.TaskID)
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TimeEntry{}, errors.Join(models.ErrCreateTimeEntry, err)
+	}
+	defer tx.Rollback()
+
+	err = checkOverlap(ctx, tx, entry)
+	if errors.Is(err, models.ErrTimeEntryOverlap) {
+		return models.TimeEntry{}, err
+	}
+	if err != nil {
+		return models.TimeEntry{}, errors.Join(models.ErrCreateTimeEntry, err)
+	}
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, "INSERT INTO labor_time (task_id, start, stop) VALUES "+
+		"($1, $2, $3) RETURNING id", entry.TaskID, entry.Start, entry.Stop).Scan(&id)
+	if err != nil {
+		return models.TimeEntry{}, models.ErrCreateTimeEntry
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.TimeEntry{}, models.ErrCreateTimeEntry
+	}
+	entry.ID = &id
+	return entry, nil
+}
+
+func (r *TaskRepository) GetEntry(ctx context.Context, id uuid.UUID) (models.TimeEntry, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.GetEntry", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	row := r.conn.QueryRowContext(ctx, "SELECT id, task_id, start, stop FROM labor_time WHERE id = $1", id)
+	if err := row.Err(); err != nil {
+		return models.TimeEntry{}, models.ErrGetTimeEntry
+	}
+
+	entry := models.TimeEntry{}
+	err := row.Scan(&entry.ID, &entry.TaskID, &entry.Start, &entry.Stop)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.TimeEntry{}, models.ErrTimeEntryNotFound
+	}
+	if err != nil {
+		return models.TimeEntry{}, errors.Join(models.ErrGetTimeEntry, err)
+	}
+	return entry, nil
+}
+
+func (r *TaskRepository) SetEntry(ctx context.Context, entry models.TimeEntry) error {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.SetEntry", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Join(models.ErrChangeTimeEntry, err)
+	}
+	defer tx.Rollback()
+
+	err = checkOverlap(ctx, tx, entry)
+	if errors.Is(err, models.ErrTimeEntryOverlap) {
+		return err
+	}
+	if err != nil {
+		return errors.Join(models.ErrChangeTimeEntry, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "update labor_time set start = $2, stop = $3 WHERE id = $1 and invoice_id is null",
+		entry.ID, entry.Start, entry.Stop)
+	if err != nil {
+		return models.ErrChangeTimeEntry
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.ErrChangeTimeEntry
+	}
+	if n == 0 {
+		return r.entryNotWritten(ctx, *entry.ID, models.ErrChangeTimeEntry)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.ErrChangeTimeEntry
+	}
+	return nil
+}
+
+func (r *TaskRepository) DeleteEntry(ctx context.Context, request models.DeleteTimeEntryRequest) error {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.DeleteEntry", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	res, err := r.conn.ExecContext(ctx, "DELETE FROM labor_time WHERE id = $1 and invoice_id is null", request.ID)
+	if err != nil {
+		return models.ErrDeleteTimeEntry
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.ErrDeleteTimeEntry
+	}
+	if n == 0 {
+		return r.entryNotWritten(ctx, request.ID, models.ErrDeleteTimeEntry)
+	}
+	return nil
+}
+
+// entryNotWritten tells why a write to the entry matched no row: it is
+// unknown, or it is frozen because it has been invoiced. failed is returned
+// if that can't be found out.
+func (r *TaskRepository) entryNotWritten(ctx context.Context, id uuid.UUID, failed error) error {
+	var invoiced bool
+	err := r.conn.QueryRowContext(ctx, "SELECT invoice_id is not null FROM labor_time WHERE id = $1", id).Scan(&invoiced)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrTimeEntryNotFound
+	}
+	if err != nil {
+		return errors.Join(failed, err)
+	}
+	if invoiced {
+		return models.ErrTimeEntryInvoiced
+	}
+	return models.ErrTimeEntryNotFound
+}
+
+// checkOverlap returns models.ErrTimeEntryOverlap if the entry interval
+// intersects any other labor time of the task owner. Running timers are
+// treated as open-ended, and an entry without a start is a timer starting now.
+//
+// The owner stays locked until tx ends, so concurrent writes for one user
+// are checked one at a time and can't both pass.
+func checkOverlap(ctx context.Context, tx *sql.Tx, entry models.TimeEntry) error {
+	var owner uuid.UUID
+	err := tx.QueryRowContext(ctx, `select u.id
+from users u
+         join tasks t on t.user_id = u.id
+where t.id = $1
+for update of u`, entry.TaskID).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrTaskNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	exclude := uuid.Nil
+	if entry.ID != nil {
+		exclude = *entry.ID
+	}
+
+	var overlap bool
+	err = tx.QueryRowContext(ctx, `select exists(select 1
+              from labor_time l
+                       join tasks t on t.id = l.task_id
+              where t.user_id = $1
+                and l.id <> $2
+                and l.start < coalesce($4::timestamp, 'infinity')
+                and coalesce(l.stop, 'infinity') > coalesce($3::timestamp, now() at time zone 'utc'))`,
+		owner, exclude, entry.Start, entry.Stop).Scan(&overlap)
+	if err != nil {
+		return err
+	}
+	if overlap {
+		return models.ErrTimeEntryOverlap
+	}
+	return nil
+}
+
+// AttachTag tags the task, creating the tag in the organization of the task
+// owner on first use. Attaching a tag twice is a no-op.
+func (r *TaskRepository) AttachTag(ctx context.Context, taskTag models.TaskTag) (models.Tag, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.AttachTag", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Tag{}, errors.Join(models.ErrTagTask, err)
+	}
+	defer tx.Rollback()
+
+	// The no-op update makes RETURNING yield existing tags too.
+	row := tx.QueryRowContext(ctx, `insert into tags (organization_id, name)
+select u.organization_id, $2
+from tasks t
+         join users u on u.id = t.user_id
+where t.id = $1
+on conflict (organization_id, name) do update set name = excluded.name
+returning id, name`, taskTag.TaskID, taskTag.Tag)
+	tag := models.Tag{}
+	err = row.Scan(&tag.ID, &tag.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Tag{}, models.ErrTaskNotFound
+	}
+	if err != nil {
+		return models.Tag{}, errors.Join(models.ErrTagTask, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO task_tags (task_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		taskTag.TaskID, tag.ID)
+	if err != nil {
+		return models.Tag{}, errors.Join(models.ErrTagTask, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.Tag{}, errors.Join(models.ErrTagTask, err)
+	}
+	return tag, nil
+}
+
+// DetachTag removes the tag from the task. The tag itself is kept for reuse.
+func (r *TaskRepository) DetachTag(ctx context.Context, taskTag models.TaskTag) error {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.DetachTag", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	res, err := r.conn.ExecContext(ctx, `delete
+from task_tags tt
+    using tags g
+where g.id = tt.tag_id
+  and tt.task_id = $1
+  and g.name = $2`, taskTag.TaskID, taskTag.Tag)
+	if err != nil {
+		return errors.Join(models.ErrTagTask, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Join(models.ErrTagTask, err)
+	}
+	if n == 0 {
+		return models.ErrTagNotFound
+	}
+	return nil
+}
+
+func (r *TaskRepository) Owner(ctx context.Context, taskID uuid.UUID) (uuid.UUID, error) {
+	ctx, done := repository.StartQuery(ctx, "TaskRepository.Owner", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	row := r.conn.QueryRowContext(ctx, "SELECT user_id FROM tasks WHERE id = $1", taskID)
+	if err := row.Err(); err != nil {
+		return uuid.Nil, models.ErrGetTaskResponse
+	}
+
+	var userID uuid.UUID
+	err := row.Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, models.ErrTaskNotFound
+	}
+	if err != nil {
+		return uuid.Nil, errors.Join(models.ErrGetTaskResponse, err)
+	}
+	return userID, nil
+}
+
+// RunningTimers is called on metric scrapes, outside of any request.
+func (r *TaskRepository) RunningTimers() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	r.log.Debugf("Executing query")
+	row := r.conn.QueryRowContext(ctx, "SELECT count(*) FROM labor_time WHERE stop is null")
+
+	var running int64
+	err := row.Scan(&running)
+	if err != nil {
+		return 0, models.ErrGetTaskResponse
 	}
-	return true, models.ErrTimerStarted
+	return running, nil
 }
@@ -0,0 +1,140 @@
+package team
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type TeamRepository struct {
+	conn    *sql.DB
+	timeout time.Duration
+	log     *logrus.Logger
+}
+
+func NewRepository(conn *sql.DB, timeout time.Duration, logger *logrus.Logger) *TeamRepository {
+	return &TeamRepository{
+		conn:    conn,
+		timeout: timeout,
+		log:     logger,
+	}
+}
+
+func (r *TeamRepository) Create(ctx context.Context, team models.Team) (models.Team, error) {
+	ctx, done := repository.StartQuery(ctx, "TeamRepository.Create", r.timeout)
+	defer done()
+
+	row := r.conn.QueryRowContext(ctx, "INSERT INTO teams (name, organization_id) values ($1, $2) RETURNING id",
+		team.Name, team.OrganizationID)
+	if err := row.Err(); err != nil {
+		return models.Team{}, models.ErrCreateTeamResponse
+	}
+	var id uuid.UUID
+	err := row.Scan(&id)
+	if err != nil {
+		return models.Team{}, models.ErrCreateTeamResponse
+	}
+	r.log.WithContext(ctx).Debugf("Inserted team: %v", id)
+	team.ID = &id
+	return team, nil
+}
+
+func (r *TeamRepository) Get(ctx context.Context, f models.TeamFilterRequest) (models.TeamFilterResponse, error) {
+	ctx, done := repository.StartQuery(ctx, "TeamRepository.Get", r.timeout)
+	defer done()
+
+	var teams []models.Team
+
+	builder := sq.Select("count(*) over ()", "id", "name", "organization_id").From("teams")
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	if f.Fields.ID != nil {
+		builder = builder.Where(sq.Eq{"id": f.Fields.ID})
+	}
+	if f.Fields.OrganizationID != nil {
+		builder = builder.Where(sq.Eq{"organization_id": f.Fields.OrganizationID})
+	}
+	if f.Fields.Name != nil {
+		builder = builder.Where(sq.ILike{"name": fmt.Sprintf("%%%v%%", *f.Fields.Name)})
+	}
+	if f.Limit != 0 {
+		builder = builder.Limit(f.Limit)
+	}
+	if f.Offset != 0 {
+		builder = builder.Offset(f.Offset)
+	}
+	query, args, err := builder.OrderBy("name").ToSql()
+	if err != nil {
+		return models.TeamFilterResponse{}, err
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.TeamFilterResponse{}, models.ErrGetTeamResponse
+	}
+	defer rows.Close()
+
+	result := models.TeamFilterResponse{}
+	for rows.Next() {
+		team := models.Team{}
+		err = rows.Scan(&result.Total, &team.ID, &team.Name, &team.OrganizationID)
+		if err != nil {
+			return models.TeamFilterResponse{}, models.ErrGetTeamResponse
+		}
+		teams = append(teams, team)
+	}
+	if err = rows.Err(); err != nil {
+		return models.TeamFilterResponse{}, errors.Join(models.ErrGetTeamResponse, err)
+	}
+	result.Teams = teams
+	return result, nil
+}
+
+func (r *TeamRepository) Delete(ctx context.Context, request models.DeleteTeamRequest) error {
+	ctx, done := repository.StartQuery(ctx, "TeamRepository.Delete", r.timeout)
+	defer done()
+
+	builder := sq.Delete("teams").Where(sq.Eq{"id": request.ID, "organization_id": request.OrganizationID})
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return models.ErrTeamDeleteResponse
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	_, err = r.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.ErrTeamDeleteResponse
+	}
+	return nil
+}
+
+func (r *TeamRepository) Set(ctx context.Context, team models.Team) error {
+	ctx, done := repository.StartQuery(ctx, "TeamRepository.Set", r.timeout)
+	defer done()
+
+	builder := sq.Update("teams").Where(sq.Eq{"id": team.ID, "organization_id": team.OrganizationID})
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	if team.Name != nil {
+		builder = builder.Set("name", team.Name)
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return models.ErrChangeTeamInfoResponse
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	_, err = r.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.ErrChangeTeamInfoResponse
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// sortExprs maps the sortable fields to their column. NULLs sort as empty
+// strings, so that cursors can compare them.
+var sortExprs = map[string]string{
+	"surname":    "coalesce(surname, '')",
+	"name":       "coalesce(name, '')",
+	"patronymic": "coalesce(patronymic, '')",
+	"address":    "coalesce(address, '')",
+	"role":       "coalesce(role, '')",
+}
+
+// searchExpr is the text matched by a search, it has a trigram index.
+const searchExpr = "(coalesce(surname, '') || ' ' || coalesce(name, '') || ' ' || " +
+	"coalesce(patronymic, '') || ' ' || coalesce(address, ''))"
+
+// rankKey stands for the search rank in cursors.
+const rankKey = "~rank"
+
+// sortColumn is one expression of the ORDER BY of a listing. Ties of all
+// columns are broken by the user ID.
+type sortColumn struct {
+	key  string
+	expr string
+	args []interface{}
+	desc bool
+}
+
+// sortColumns returns the order of a listing: the requested sort, or the
+// best matches first when searching without one.
+func sortColumns(f models.FilterRequest) ([]sortColumn, error) {
+	if len(f.Sort) == 0 && f.Query != "" {
+		return []sortColumn{{
+			key:  rankKey,
+			expr: "word_similarity(?, " + searchExpr + ")",
+			args: []interface{}{f.Query},
+			desc: true,
+		}}, nil
+	}
+
+	columns := make([]sortColumn, 0, len(f.Sort))
+	for _, s := range f.Sort {
+		expr, ok := sortExprs[s.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", models.ErrInvalidSort, s.Field)
+		}
+		key := s.Field
+		if s.Desc {
+			key = "-" + key
+		}
+		columns = append(columns, sortColumn{key: key, expr: expr, desc: s.Desc})
+	}
+	return columns, nil
+}
+
+// cursor is the position after the last user of a page. Sort is kept to
+// reject cursors replayed with another order.
+type cursor struct {
+	Sort   string    `json:"s,omitempty"`
+	Values []string  `json:"v,omitempty"`
+	ID     uuid.UUID `json:"id"`
+}
+
+func sortKey(columns []sortColumn) string {
+	keys := make([]string, 0, len(columns))
+	for _, c := range columns {
+		keys = append(keys, c.key)
+	}
+	return strings.Join(keys, ",")
+}
+
+// encodeCursor takes the values of the sort columns as selected for the last
+// user of a page.
+func encodeCursor(columns []sortColumn, values []string, id uuid.UUID) string {
+	data, _ := json.Marshal(cursor{Sort: sortKey(columns), Values: values, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// after decodes s and returns the condition selecting the users following it
+// in the given order.
+func after(s string, columns []sortColumn) (sq.Sqlizer, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	c := cursor{}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	if c.Sort != sortKey(columns) || len(c.Values) != len(columns) {
+		return nil, fmt.Errorf("%w: it was issued for another sort", models.ErrInvalidCursor)
+	}
+
+	// Mixed directions rule out a row comparison, so this expands to
+	// a > x or (a = x and b < y) or (a = x and b = y and id > z).
+	or := sq.Or{}
+	for i := 0; i <= len(columns); i++ {
+		and := sq.And{}
+		for j := 0; j < i; j++ {
+			and = append(and, compare(columns[j], "=", c.Values[j]))
+		}
+		switch {
+		case i == len(columns):
+			and = append(and, sq.Expr("id > ?", c.ID))
+		case columns[i].desc:
+			and = append(and, compare(columns[i], "<", c.Values[i]))
+		default:
+			and = append(and, compare(columns[i], ">", c.Values[i]))
+		}
+		or = append(or, and)
+	}
+	return or, nil
+}
+
+func compare(column sortColumn, op string, value string) sq.Sqlizer {
+	args := append(append([]interface{}{}, column.args...), value)
+	return sq.Expr(column.expr+" "+op+" ?", args...)
+}
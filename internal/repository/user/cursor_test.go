@@ -0,0 +1,137 @@
+package user
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+)
+
+func TestSortColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.FilterRequest
+		keys   []string
+		err    error
+	}{
+		{"unsorted", models.FilterRequest{}, []string{}, nil},
+		{"sorted", models.FilterRequest{Sort: []models.SortField{{Field: "surname"}, {Field: "name", Desc: true}}},
+			[]string{"surname", "-name"}, nil},
+		{"search ranks", models.FilterRequest{Query: "ivan"}, []string{rankKey}, nil},
+		{"sort beats rank", models.FilterRequest{Query: "ivan", Sort: []models.SortField{{Field: "role"}}},
+			[]string{"role"}, nil},
+		{"unknown field", models.FilterRequest{Sort: []models.SortField{{Field: "passport"}}}, nil,
+			models.ErrInvalidSort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := sortColumns(tt.filter)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			keys := []string{}
+			for _, c := range columns {
+				keys = append(keys, c.key)
+			}
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Fatalf("got keys %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestAfter(t *testing.T) {
+	id := uuid.MustParse("6f1c2a56-1a4e-4c5e-9d1b-0c3f4e5a6b7c")
+	sorted := []models.SortField{{Field: "surname"}, {Field: "name", Desc: true}}
+
+	tests := []struct {
+		name   string
+		filter models.FilterRequest
+		values []string
+		sql    string
+		args   []interface{}
+	}{
+		{
+			name:   "by id only",
+			filter: models.FilterRequest{},
+			values: []string{},
+			sql:    "((id > ?))",
+			args:   []interface{}{id},
+		},
+		{
+			name:   "ascending",
+			filter: models.FilterRequest{Sort: []models.SortField{{Field: "surname"}}},
+			values: []string{"Ivanov"},
+			sql:    "((coalesce(surname, '') > ?) OR (coalesce(surname, '') = ? AND id > ?))",
+			args:   []interface{}{"Ivanov", "Ivanov", id},
+		},
+		{
+			name:   "mixed directions",
+			filter: models.FilterRequest{Sort: sorted},
+			values: []string{"Ivanov", "Ivan"},
+			sql: "((coalesce(surname, '') > ?) OR (coalesce(surname, '') = ? AND coalesce(name, '') < ?) OR " +
+				"(coalesce(surname, '') = ? AND coalesce(name, '') = ? AND id > ?))",
+			args: []interface{}{"Ivanov", "Ivanov", "Ivan", "Ivanov", "Ivan", id},
+		},
+		{
+			name:   "search rank descends",
+			filter: models.FilterRequest{Query: "ivan"},
+			values: []string{"0.5"},
+			sql: "((word_similarity(?, " + searchExpr + ") < ?) OR " +
+				"(word_similarity(?, " + searchExpr + ") = ? AND id > ?))",
+			args: []interface{}{"ivan", "0.5", "ivan", "0.5", id},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := sortColumns(tt.filter)
+			if err != nil {
+				t.Fatalf("sortColumns: %v", err)
+			}
+			where, err := after(encodeCursor(columns, tt.values, id), columns)
+			if err != nil {
+				t.Fatalf("after: %v", err)
+			}
+			sql, args, err := where.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql: %v", err)
+			}
+			if sql != tt.sql {
+				t.Errorf("got sql\n%s\nwant\n%s", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("got args %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestAfterRejects(t *testing.T) {
+	id := uuid.New()
+	bySurname, _ := sortColumns(models.FilterRequest{Sort: []models.SortField{{Field: "surname"}}})
+	bySurnameDesc, _ := sortColumns(models.FilterRequest{Sort: []models.SortField{{Field: "surname", Desc: true}}})
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("surname"))},
+		{"other direction", encodeCursor(bySurnameDesc, []string{"Ivanov"}, id)},
+		{"other field", encodeCursor(nil, nil, id)},
+		{"missing values", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"surname","id":"` + id.String() + `"}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := after(tt.cursor, bySurname)
+			if !errors.Is(err, models.ErrInvalidCursor) {
+				t.Fatalf("got %v, want %v", err, models.ErrInvalidCursor)
+			}
+		})
+	}
+}
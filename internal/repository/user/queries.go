@@ -1,67 +1,108 @@
 package user
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/repository"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"time"
 )
 
 type UserRepository struct {
-	conn *sql.DB
-	log  *logrus.Logger
+	conn    *sql.DB
+	cipher  Cipher
+	timeout time.Duration
+	log     *logrus.Logger
 }
 
-func NewRepository(conn *sql.DB, logger *logrus.Logger) *UserRepository {
+// Cipher encrypts passports at rest. Lookups by passport go through the
+// blind index because the ciphertext differs on every write.
+type Cipher interface {
+	Encrypt(value string) (string, error)
+	Decrypt(value string) (string, error)
+	BlindIndex(value string) string
+}
+
+func NewRepository(conn *sql.DB, cipher Cipher, timeout time.Duration, logger *logrus.Logger) *UserRepository {
 	return &UserRepository{
-		conn: conn,
-		log:  logger,
+		conn:    conn,
+		cipher:  cipher,
+		timeout: timeout,
+		log:     logger,
 	}
 }
 
-func (r *UserRepository) Create(user models.User) (models.User, error) {
-	row := r.conn.QueryRow("INSERT INTO users (passport, name, surname, patronymic, address) values "+
-		"($1, $2, $3, $4, $5) RETURNING id", user.Passport, user.Name, user.Surname, user.Patronymic, user.Address)
-	if err := row.Err(); err != nil {
+func (r *UserRepository) Create(ctx context.Context, user models.User) (models.User, error) {
+	ctx, done := repository.StartQuery(ctx, "UserRepository.Create", r.timeout)
+	defer done()
+
+	passport, passportIndex, err := r.encryptPassport(user.Passport)
+	if err != nil {
 		return models.User{}, models.ErrCreateUserResponse
 	}
+
+	row := r.conn.QueryRowContext(ctx, "INSERT INTO users (passport, passport_index, name, surname, patronymic, address, "+
+		"password_hash, organization_id, role) values ($1, $2, $3, $4, $5, $6, $7, $8, coalesce($9, 'employee')) "+
+		"RETURNING id, role", passport, passportIndex, user.Name, user.Surname, user.Patronymic, user.Address,
+		user.PasswordHash, user.OrganizationID, user.Role)
 	var id uuid.UUID
-	err := row.Scan(&id)
+	err = row.Scan(&id, &user.Role)
+	if repository.IsUniqueViolation(err) {
+		return models.User{}, models.ErrUserExists
+	}
 	if err != nil {
 		return models.User{}, models.ErrCreateUserResponse
 	}
-	r.log.Debugf("Inserted user: %v", id)
+	r.log.WithContext(ctx).Debugf("Inserted user: %v", id)
 	user.ID = &id
 	return user, nil
 }
 
-func (r *UserRepository) Get(f models.FilterRequest) (models.FilterResponse, error) {
-	var users []models.User
+// Get lists the users matching the filter. Pages are continued either by
+// Offset or, faster on large tables, by the returned NextCursor. A search
+// query matches users with a similar name or address, typos included.
+func (r *UserRepository) Get(ctx context.Context, f models.FilterRequest) (models.FilterResponse, error) {
+	ctx, done := repository.StartQuery(ctx, "UserRepository.Get", r.timeout)
+	defer done()
 
-	builder := sq.Select("count(*) over ()", "id", "passport", "name", "surname", "patronymic", "address").From("users")
-	builder = builder.PlaceholderFormat(sq.Dollar)
-	if f.Fields.ID != nil {
-		builder = builder.Where(sq.Eq{"id": f.Fields.ID})
-	}
-	if f.Fields.Name != nil {
-		builder = builder.Where(sq.ILike{"name": fmt.Sprintf("%%%v%%", *f.Fields.Name)})
+	where := r.conditions(f.Fields)
+	if f.Query != "" {
+		// <% is word similarity, it uses the trigram index of searchExpr.
+		where = append(where, sq.Expr("? <% "+searchExpr, f.Query))
 	}
-	if f.Fields.Surname != nil {
-		builder = builder.Where(sq.ILike{"surname": fmt.Sprintf("%%%v%%", *f.Fields.Surname)})
-	}
-	if f.Fields.Patronymic != nil {
-		builder = builder.Where(sq.ILike{"patronymic": fmt.Sprintf("%%%v%%", *f.Fields.Patronymic)})
+	columns, err := sortColumns(f)
+	if err != nil {
+		return models.FilterResponse{}, err
 	}
-	if f.Fields.Address != nil {
-		builder = builder.Where(sq.ILike{"address": fmt.Sprintf("%%%v%%", *f.Fields.Address)})
+
+	builder := sq.Select("id", "passport", "name", "surname", "patronymic", "address", "role",
+		"organization_id", "team_id", "hourly_rate", "rate_currency").From("users").Where(where)
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	// The sort values are selected as text for the cursor of the next page.
+	for _, c := range columns {
+		builder = builder.Column(sq.Expr("("+c.expr+")::text", c.args...))
+		if c.desc {
+			builder = builder.OrderByClause(c.expr+" desc", c.args...)
+		} else {
+			builder = builder.OrderByClause(c.expr, c.args...)
+		}
 	}
-	if f.Fields.Passport != nil {
-		builder = builder.Where(sq.ILike{"passport": fmt.Sprintf("%%%v%%", *f.Fields.Passport)})
+	builder = builder.OrderBy("id")
+	if f.Cursor != "" {
+		next, err := after(f.Cursor, columns)
+		if err != nil {
+			return models.FilterResponse{}, err
+		}
+		builder = builder.Where(next)
 	}
+	// One more row than asked for tells whether there is a next page.
 	if f.Limit != 0 {
-		builder = builder.Limit(f.Limit)
+		builder = builder.Limit(f.Limit + 1)
 	}
 	if f.Offset != 0 {
 		builder = builder.Offset(f.Offset)
@@ -71,44 +112,141 @@ func (r *UserRepository) Get(f models.FilterRequest) (models.FilterResponse, err
 		return models.FilterResponse{}, err
 	}
 
-	r.log.Debugf("Executing query: %v", query)
-	rows, err := r.conn.Query(query, args...)
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	rows, err := r.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		return models.FilterResponse{}, models.ErrGetUserResponse
 	}
+	defer rows.Close()
 
-	result := models.FilterResponse{}
+	var users []models.User
+	var last []string
 	for rows.Next() {
 		user := models.User{}
-		err = rows.Scan(&result.Total, &user.ID, &user.Passport, &user.Name, &user.Surname, &user.Patronymic, &user.Address)
+		rate := repository.NullRate{}
+		values := make([]string, len(columns))
+		dest := []interface{}{&user.ID, &user.Passport, &user.Name, &user.Surname, &user.Patronymic, &user.Address,
+			&user.Role, &user.OrganizationID, &user.TeamID, &rate.Amount, &rate.Currency}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		err = rows.Scan(dest...)
 		if err != nil {
 			return models.FilterResponse{}, models.ErrGetUserResponse
 		}
+		user.HourlyRate = rate.Rate()
+		if user.Passport != nil {
+			passport, err := r.cipher.Decrypt(*user.Passport)
+			if err != nil {
+				return models.FilterResponse{}, models.ErrGetUserResponse
+			}
+			user.Passport = &passport
+		}
 		users = append(users, user)
+		if f.Limit == 0 || uint64(len(users)) <= f.Limit {
+			last = values
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return models.FilterResponse{}, models.ErrGetUserResponse
+	}
+
+	result := models.FilterResponse{}
+	if f.Limit != 0 && uint64(len(users)) > f.Limit {
+		users = users[:f.Limit]
+		result.NextCursor = encodeCursor(columns, last, *users[len(users)-1].ID)
+	}
+	if f.IncludeTotal {
+		result.Total, err = r.count(ctx, where)
+		if err != nil {
+			return models.FilterResponse{}, err
+		}
 	}
-	r.log.Debugf("Returning users: %v", users)
+
+	r.log.WithContext(ctx).Debugf("Returning %d users", len(users))
 	result.Users = users
 	return result, nil
 }
 
-func (r *UserRepository) Delete(request models.DeleteUserRequest) error {
-	builder := sq.Delete("users").Where(sq.Eq{"id": request.ID})
+func (r *UserRepository) count(ctx context.Context, where sq.Sqlizer) (int64, error) {
+	query, args, err := sq.Select("count(*)").From("users").Where(where).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	var total int64
+	err = r.conn.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return 0, models.ErrGetUserResponse
+	}
+	return total, nil
+}
+
+func (r *UserRepository) conditions(u models.User) sq.And {
+	where := sq.And{}
+	if u.ID != nil {
+		where = append(where, sq.Eq{"id": u.ID})
+	}
+	if u.OrganizationID != nil {
+		where = append(where, sq.Eq{"organization_id": u.OrganizationID})
+	}
+	if u.TeamID != nil {
+		where = append(where, sq.Eq{"team_id": u.TeamID})
+	}
+	if u.Name != nil {
+		where = append(where, sq.ILike{"name": fmt.Sprintf("%%%v%%", *u.Name)})
+	}
+	if u.Surname != nil {
+		where = append(where, sq.ILike{"surname": fmt.Sprintf("%%%v%%", *u.Surname)})
+	}
+	if u.Patronymic != nil {
+		where = append(where, sq.ILike{"patronymic": fmt.Sprintf("%%%v%%", *u.Patronymic)})
+	}
+	if u.Address != nil {
+		where = append(where, sq.ILike{"address": fmt.Sprintf("%%%v%%", *u.Address)})
+	}
+	if u.Passport != nil {
+		where = append(where, sq.Eq{"passport_index": r.cipher.BlindIndex(*u.Passport)})
+	}
+	return where
+}
+
+func (r *UserRepository) Delete(ctx context.Context, request models.DeleteUserRequest) error {
+	ctx, done := repository.StartQuery(ctx, "UserRepository.Delete", r.timeout)
+	defer done()
+
+	builder := sq.Delete("users").Where(sq.Eq{"id": request.ID, "organization_id": request.OrganizationID})
 	builder = builder.PlaceholderFormat(sq.Dollar)
 	query, args, err := builder.ToSql()
 	if err != nil {
 		return models.ErrUserDeleteResponse
 	}
 
-	r.log.Debugf("Executing query: %v", query)
-	_, err = r.conn.Exec(query, args...)
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	res, err := r.conn.ExecContext(ctx, query, args...)
 	if err != nil {
 		return models.ErrUserDeleteResponse
 	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.ErrUserDeleteResponse
+	}
+	if n == 0 {
+		return models.ErrUserNotFound
+	}
 	return nil
 }
 
-func (r *UserRepository) Set(user models.User) error {
-	builder := sq.Update("users").Where(sq.Eq{"id": user.ID})
+// Set returns models.ErrUserNotFound when no user of the organization has
+// the ID and models.ErrTeamNotFound when the new team isn't one of the
+// organization's.
+func (r *UserRepository) Set(ctx context.Context, user models.User) error {
+	ctx, done := repository.StartQuery(ctx, "UserRepository.Set", r.timeout)
+	defer done()
+
+	builder := sq.Update("users").Where(sq.Eq{"id": user.ID, "organization_id": user.OrganizationID})
 	builder = builder.PlaceholderFormat(sq.Dollar)
 	if user.Name != nil {
 		builder = builder.Set("name", user.Name)
@@ -123,7 +261,22 @@ func (r *UserRepository) Set(user models.User) error {
 		builder = builder.Set("address", user.Address)
 	}
 	if user.Passport != nil {
-		builder = builder.Set("passport", user.Passport)
+		passport, passportIndex, err := r.encryptPassport(user.Passport)
+		if err != nil {
+			return models.ErrChangeUserInfoResponse
+		}
+		builder = builder.Set("passport", passport).Set("passport_index", passportIndex)
+	}
+	if user.Role != nil {
+		builder = builder.Set("role", user.Role)
+	}
+	if user.HourlyRate != nil {
+		amount, currency := repository.RateValues(*user.HourlyRate)
+		builder = builder.Set("hourly_rate", amount).Set("rate_currency", currency)
+	}
+	if user.TeamID != nil {
+		builder = builder.Set("team_id", user.TeamID).
+			Where("exists (select 1 from teams where id = ? and organization_id = ?)", user.TeamID, user.OrganizationID)
 	}
 
 	query, args, err := builder.ToSql()
@@ -131,10 +284,147 @@ func (r *UserRepository) Set(user models.User) error {
 		return models.ErrChangeUserInfoResponse
 	}
 
-	r.log.Debugf("Executing query: %v", query)
-	_, err = r.conn.Exec(query, args...)
+	r.log.WithContext(ctx).Debugf("Executing query: %v", query)
+	res, err := r.conn.ExecContext(ctx, query, args...)
+	if repository.IsUniqueViolation(err) {
+		return models.ErrUserExists
+	}
+	if err != nil {
+		return models.ErrChangeUserInfoResponse
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.ErrChangeUserInfoResponse
+	}
+	if n == 0 && user.TeamID != nil {
+		return r.teamNotFound(ctx, *user.TeamID, *user.OrganizationID)
+	}
+	if n == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// teamNotFound tells which condition of a team assignment matched no row.
+func (r *UserRepository) teamNotFound(ctx context.Context, teamID, organizationID uuid.UUID) error {
+	var exists bool
+	err := r.conn.QueryRowContext(ctx, "SELECT exists(SELECT 1 FROM teams WHERE id = $1 and organization_id = $2)",
+		teamID, organizationID).Scan(&exists)
+	if err != nil {
+		return models.ErrChangeUserInfoResponse
+	}
+	if !exists {
+		return models.ErrTeamNotFound
+	}
+	return models.ErrUserNotFound
+}
+
+func (r *UserRepository) GetCredentials(ctx context.Context, passport string) (models.Credentials, error) {
+	ctx, done := repository.StartQuery(ctx, "UserRepository.GetCredentials", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	row := r.conn.QueryRowContext(ctx, "SELECT id, password_hash, role, organization_id, team_id FROM users "+
+		"WHERE passport_index = $1 and password_hash is not null", r.cipher.BlindIndex(passport))
+	if err := row.Err(); err != nil {
+		return models.Credentials{}, models.ErrGetUserResponse
+	}
+
+	credentials := models.Credentials{}
+	err := row.Scan(&credentials.Caller.ID, &credentials.PasswordHash, &credentials.Caller.Role,
+		&credentials.Caller.OrganizationID, &credentials.Caller.TeamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Credentials{}, models.ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.Credentials{}, models.ErrGetUserResponse
+	}
+	return credentials, nil
+}
+
+func (r *UserRepository) GetCaller(ctx context.Context, userID uuid.UUID) (models.Caller, error) {
+	ctx, done := repository.StartQuery(ctx, "UserRepository.GetCaller", r.timeout)
+	defer done()
+
+	r.log.WithContext(ctx).Debugf("Executing query")
+	row := r.conn.QueryRowContext(ctx, "SELECT id, role, organization_id, team_id FROM users WHERE id = $1", userID)
+	if err := row.Err(); err != nil {
+		return models.Caller{}, models.ErrGetUserResponse
+	}
+
+	caller := models.Caller{}
+	err := row.Scan(&caller.ID, &caller.Role, &caller.OrganizationID, &caller.TeamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Caller{}, models.ErrInvalidToken
+	}
+	if err != nil {
+		return models.Caller{}, models.ErrGetUserResponse
+	}
+	return caller, nil
+}
+
+// EncryptPassports encrypts and indexes passports stored before encryption
+// was introduced. It runs once on startup and isn't bound by the query timeout.
+func (r *UserRepository) EncryptPassports(ctx context.Context) error {
+	r.log.WithContext(ctx).Debugf("Executing query")
+	rows, err := r.conn.QueryContext(ctx, "SELECT id, passport FROM users WHERE passport is not null and passport_index is null "+
+		"and passport not like 'v1:%'")
 	if err != nil {
 		return models.ErrChangeUserInfoResponse
 	}
+	defer rows.Close()
+
+	legacy := map[uuid.UUID]string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var passport string
+		err = rows.Scan(&id, &passport)
+		if err != nil {
+			return models.ErrChangeUserInfoResponse
+		}
+		legacy[id] = passport
+	}
+	if err = rows.Err(); err != nil {
+		return models.ErrChangeUserInfoResponse
+	}
+
+	for id, value := range legacy {
+		plain, err := r.cipher.Decrypt(value)
+		if err != nil {
+			return models.ErrChangeUserInfoResponse
+		}
+		passport, passportIndex, err := r.encryptPassport(&plain)
+		if err != nil {
+			return models.ErrChangeUserInfoResponse
+		}
+		_, err = r.conn.ExecContext(ctx, "UPDATE users SET passport = $1, passport_index = $2 WHERE id = $3", passport,
+			passportIndex, id)
+		if repository.IsUniqueViolation(err) {
+			// Another user already has the passport. The value is still
+			// encrypted but left out of the index, so only the other user
+			// can log in with it.
+			r.log.WithContext(ctx).Warnf("User %v has a duplicate passport, it is encrypted without an index", id)
+			_, err = r.conn.ExecContext(ctx, "UPDATE users SET passport = $1 WHERE id = $2", passport, id)
+		}
+		if err != nil {
+			return models.ErrChangeUserInfoResponse
+		}
+	}
+	if len(legacy) > 0 {
+		r.log.WithContext(ctx).Infof("Encrypted %d stored passports", len(legacy))
+	}
 	return nil
 }
+
+func (r *UserRepository) encryptPassport(passport *string) (*string, *string, error) {
+	if passport == nil {
+		return nil, nil, nil
+	}
+	encrypted, err := r.cipher.Encrypt(*passport)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := r.cipher.BlindIndex(*passport)
+	return &encrypted, &index, nil
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type Auth interface {
+	Register(ctx context.Context, person models.CreateUserRequest) (models.User, error)
+	Login(ctx context.Context, request models.LoginRequest) (models.TokenResponse, error)
+	Refresh(ctx context.Context, request models.RefreshRequest) (models.TokenResponse, error)
+	Authenticate(token string) (models.Caller, error)
+}
+
+type Handler struct {
+	service Auth
+	log     *logrus.Logger
+}
+
+func NewHandler(service Auth, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/register", rs.register)
+	r.Post("/login", rs.login)
+	r.Post("/refresh", rs.refresh)
+
+	return r
+}
+
+// @Summary Register
+// @Description Handles request to create a new user with a password by passportNumber and returns the user information in JSON with the passport masked. The user joins the organization configured for self-registration.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateUserRequest true "Passport and password"
+// @Success 200 {object} models.User "Created user"
+// @Failure 400
+// @Failure 409
+// @Failure 500
+// @Router /auth/register [post]
+func (rs *Handler) register(w http.ResponseWriter, r *http.Request) {
+	p := models.CreateUserRequest{}
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil || p.PassportNumber == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Registering new user")
+	newUser, err := rs.service.Register(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+	newUser.MaskPassport()
+
+	rs.writeJSON(w, r, newUser)
+}
+
+// @Summary Log in
+// @Description Handles request to exchange passportNumber and password for an access and a refresh token.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.LoginRequest true "Passport and password"
+// @Success 200 {object} models.TokenResponse "Access and refresh tokens"
+// @Failure 400
+// @Failure 401
+// @Failure 500
+// @Router /auth/login [post]
+func (rs *Handler) login(w http.ResponseWriter, r *http.Request) {
+	request := models.LoginRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.PassportNumber == nil || request.Password == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Logging in")
+	tokens, err := rs.service.Login(r.Context(), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, tokens)
+}
+
+// @Summary Refresh tokens
+// @Description Handles request to exchange a refresh token for a new pair of tokens.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse "Access and refresh tokens"
+// @Failure 400
+// @Failure 401
+// @Failure 500
+// @Router /auth/refresh [post]
+func (rs *Handler) refresh(w http.ResponseWriter, r *http.Request) {
+	request := models.RefreshRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.RefreshToken == "" {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Refreshing tokens")
+	tokens, err := rs.service.Refresh(r.Context(), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, tokens)
+}
+
+func (rs *Handler) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/VikaPaz/time_tracker/internal/logging"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+)
+
+type callerKey struct{}
+
+// Middleware rejects requests without a valid bearer access token and puts
+// the caller into the request context.
+func (rs *Handler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			problem.Write(w, r, fmt.Errorf("%w: missing bearer token", models.ErrInvalidToken))
+			return
+		}
+
+		caller, err := rs.service.Authenticate(token)
+		if err != nil {
+			rs.log.WithContext(r.Context()).Error(err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.Write(w, r, err)
+			return
+		}
+
+		logging.SetUserID(r.Context(), caller.ID)
+		next.ServeHTTP(w, r.WithContext(WithCaller(r.Context(), caller)))
+	})
+}
+
+func WithCaller(ctx context.Context, caller models.Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// Caller returns the authenticated caller, or a zero Caller outside of Middleware.
+func Caller(ctx context.Context) models.Caller {
+	caller, _ := ctx.Value(callerKey{}).(models.Caller)
+	return caller
+}
+
+// UserID returns the authenticated caller's ID, or uuid.Nil outside of Middleware.
+func UserID(ctx context.Context) uuid.UUID {
+	return Caller(ctx).ID
+}
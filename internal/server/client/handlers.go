@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type Client interface {
+	CreateClient(ctx context.Context, client models.Client) (models.Client, error)
+	DeleteClient(ctx context.Context, request models.DeleteClientRequest) error
+	ChangeClient(ctx context.Context, client models.Client) error
+	GetClients(ctx context.Context, request models.ClientFilterRequest) (models.ClientFilterResponse, error)
+}
+
+type Handler struct {
+	service Client
+	log     *logrus.Logger
+}
+
+func NewHandler(service Client, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/new", rs.new)
+	r.Delete("/delete", rs.del)
+	r.Get("/get", rs.get)
+	r.Patch("/set", rs.change)
+
+	return r
+}
+
+// @Summary Creating a new client
+// @Description Handles request to create a new client and returns the client information in JSON. Requires the admin or manager role.
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Client true "Client name"
+// @Success 200 {object} models.Client "Created client"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /client/new [post]
+func (rs *Handler) new(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageProjects(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	c := models.Client{}
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil || c.Name == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	c.OrganizationID = &organizationID
+
+	rs.log.WithContext(r.Context()).Infof("Creating new client")
+	newClient, err := rs.service.CreateClient(r.Context(), c)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(newClient)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Delete client
+// @Description Handles request to delete a client by ID. Projects of the client are kept without a client. Requires the admin or manager role.
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DeleteClientRequest true "Client ID"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /client/delete [delete]
+func (rs *Handler) del(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageProjects(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	request := models.DeleteClientRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	request.OrganizationID = auth.Caller(r.Context()).OrganizationID
+	rs.log.WithContext(r.Context()).Infof("Deleting client: %v", request.ID)
+	err = rs.service.DeleteClient(r.Context(), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
+
+// @Summary Get clients
+// @Description Handles request to get clients by filter.
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param fields.id query string false "Client ID"
+// @Param fields.name query string false "Client name"
+// @Param limit query int false "Maximum number of results"
+// @Param offset query int false "Offset from the beginning of results"
+// @Success 200 {object}  models.ClientFilterResponse "List of clients and total results"
+// @Failure 400
+// @Failure 401
+// @Failure 500
+// @Router /client/get [get]
+func (rs *Handler) get(w http.ResponseWriter, r *http.Request) {
+	filter := models.ClientFilterRequest{}
+	params := r.URL.Query()
+
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	filter.Fields.OrganizationID = &organizationID
+
+	if idStr := params.Get("fields.id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid UUID for fields.id")
+			return
+		}
+		filter.Fields.ID = &id
+	}
+	if name := params.Get("fields.name"); name != "" {
+		filter.Fields.Name = &name
+	}
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.ParseUint(limitStr, 10, 64)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid limit parameter")
+			return
+		}
+		filter.Limit = limit
+	}
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		offset, err := strconv.ParseUint(offsetStr, 10, 64)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid offset parameter")
+			return
+		}
+		filter.Offset = offset
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Getting clients")
+	clients, err := rs.service.GetClients(r.Context(), filter)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(clients)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Update client
+// @Description Handles request to update client information. Requires the admin or manager role.
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Client true "Client Information"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /client/set [patch]
+func (rs *Handler) change(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageProjects(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	c := models.Client{}
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil || c.ID == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	c.OrganizationID = &organizationID
+
+	rs.log.WithContext(r.Context()).Infof("Changing client information")
+	err = rs.service.ChangeClient(r.Context(), c)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type Health interface {
+	Live() models.HealthResponse
+	Ready(ctx context.Context) models.HealthResponse
+	Version() (models.VersionResponse, error)
+}
+
+type Handler struct {
+	service Health
+	log     *logrus.Logger
+}
+
+func NewHandler(service Health, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/healthz", rs.healthz)
+	r.Get("/readyz", rs.readyz)
+	r.Get("/version", rs.version)
+
+	return r
+}
+
+// @Summary Liveness
+// @Description Handles request to check that the process is alive. It does not touch any dependency.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse "Process is alive"
+// @Router /healthz [get]
+func (rs *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	rs.writeJSON(w, r, http.StatusOK, rs.service.Live())
+}
+
+// @Summary Readiness
+// @Description Handles request to check that the service can serve traffic: Postgres answers, migrations are at the latest version and, if enabled, the people info server is reachable. Every dependency is reported with its status and latency.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse "All dependencies are up"
+// @Failure 503 {object} models.HealthResponse "Some dependency is down"
+// @Router /readyz [get]
+func (rs *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	response := rs.service.Ready(r.Context())
+
+	status := http.StatusOK
+	if response.Status != models.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	rs.writeJSON(w, r, status, response)
+}
+
+// @Summary Version
+// @Description Handles request to get the build information of the running binary.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.VersionResponse "Build information"
+// @Failure 500
+// @Router /version [get]
+func (rs *Handler) version(w http.ResponseWriter, r *http.Request) {
+	response, err := rs.service.Version()
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+	rs.writeJSON(w, r, http.StatusOK, response)
+}
+
+func (rs *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
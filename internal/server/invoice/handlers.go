@@ -0,0 +1,185 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type Invoice interface {
+	CreateInvoice(ctx context.Context, request models.InvoiceRequest) (models.Invoice, error)
+	WriteInvoice(ctx context.Context, request models.InvoiceExportRequest, w io.Writer) error
+}
+
+type Handler struct {
+	service Invoice
+	log     *logrus.Logger
+}
+
+func NewHandler(service Invoice, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/", rs.create)
+	r.Get("/{id}", rs.get)
+
+	return r
+}
+
+// @Summary Create invoice
+// @Description Handles request to invoice the unbilled labor time of a client: finished segments inside the window, of billable tasks in the client's projects with an hourly rate. Each task and rate becomes a line, the tax is added to the subtotal. The segments are marked as invoiced so they are never billed twice. Requires the admin or manager role.
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.InvoiceRequest true "Client, window, currency and tax rate"
+// @Success 201 {object} models.Invoice "Issued invoice"
+// @Header 201 {string} Location "Path of the invoice"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /invoices [post]
+func (rs *Handler) create(w http.ResponseWriter, r *http.Request) {
+	caller := auth.Caller(r.Context())
+	if err := policy.ManageInvoices(caller); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	p := models.InvoiceRequest{}
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil || p.ClientID == uuid.Nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	p.OrganizationID = caller.OrganizationID
+	p.CreatedBy = caller.ID
+
+	rs.log.WithContext(r.Context()).Infof("Creating invoice")
+	invoice, err := rs.service.CreateInvoice(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(invoice)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+invoice.ID.String())
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Get invoice
+// @Description Handles request to get an invoice as JSON, PDF or a UBL 2.1 XML document for accounting software. The format is taken from the format parameter or the Accept header, JSON by default. Requires the admin or manager role.
+// @Tags invoices
+// @Produce json
+// @Produce application/pdf
+// @Produce application/xml
+// @Security BearerAuth
+// @Param id path string true "Invoice ID"
+// @Param format query string false "Document format" Enums(json, pdf, ubl)
+// @Success 200 {object} models.Invoice "Invoice"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /invoices/{id} [get]
+func (rs *Handler) get(w http.ResponseWriter, r *http.Request) {
+	caller := auth.Caller(r.Context())
+	if err := policy.ManageInvoices(caller); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.BadRequest(w, r, "Invalid UUID for id")
+		return
+	}
+
+	p := models.InvoiceExportRequest{
+		ID:             id,
+		OrganizationID: caller.OrganizationID,
+		Format:         invoiceFormat(r),
+	}
+	contentType, ok := invoiceContentTypes[p.Format]
+	if !ok {
+		problem.BadRequest(w, r, "Invalid format parameter (json, pdf or ubl expected)")
+		return
+	}
+
+	// Documents are rendered in full before anything is sent, so a missing
+	// invoice still gets a problem response.
+	var buf bytes.Buffer
+	rs.log.WithContext(r.Context()).Infof("Getting invoice: %v", id)
+	err = rs.service.WriteInvoice(r.Context(), p, &buf)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if p.Format != models.InvoiceJSON {
+		w.Header().Set("Content-Disposition", `attachment; filename="invoice-`+id.String()+invoiceExtensions[p.Format]+`"`)
+	}
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+var invoiceContentTypes = map[string]string{
+	models.InvoiceJSON: "application/json",
+	models.InvoicePDF:  "application/pdf",
+	models.InvoiceUBL:  "application/xml",
+}
+
+var invoiceExtensions = map[string]string{
+	models.InvoicePDF: ".pdf",
+	models.InvoiceUBL: ".xml",
+}
+
+func invoiceFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	for format, contentType := range invoiceContentTypes {
+		if format != models.InvoiceJSON && strings.Contains(accept, contentType) {
+			return format
+		}
+	}
+	return models.InvoiceJSON
+}
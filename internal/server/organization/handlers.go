@@ -0,0 +1,87 @@
+package organization
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type Organization interface {
+	CreateOrganization(ctx context.Context, request models.CreateOrganizationRequest) (models.CreateOrganizationResponse, error)
+}
+
+type Handler struct {
+	service Organization
+	log     *logrus.Logger
+}
+
+func NewHandler(service Organization, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/new", rs.new)
+
+	return r
+}
+
+// @Summary Creating a new organization
+// @Description Handles request to create a new organization together with its first admin, who signs in with the given passport and password and invites the other members. Requires the admin role.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateOrganizationRequest true "Organization name and first admin"
+// @Success 200 {object} models.CreateOrganizationResponse "Created organization and admin"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 409
+// @Failure 500
+// @Router /organization/new [post]
+func (rs *Handler) new(w http.ResponseWriter, r *http.Request) {
+	if err := policy.CreateOrganization(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	request := models.CreateOrganizationRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Name == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Creating new organization")
+	result, err := rs.service.CreateOrganization(r.Context(), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
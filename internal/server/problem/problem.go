@@ -0,0 +1,204 @@
+// Package problem writes error responses as RFC 7807 application/problem+json
+// documents.
+//
+// Handlers pass whatever error a service returned to Write, which classifies
+// it by the sentinels of the models package.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/logging"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+// TypePrefix starts the type of every problem, followed by a stable slug such
+// as "user-exists".
+const TypePrefix = "urn:time-tracker:problem:"
+
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Reason is set on 403 responses, see models.AccessError.
+	Reason string `json:"reason,omitempty"`
+}
+
+type kind struct {
+	err    error
+	status int
+	slug   string
+	title  string
+}
+
+// kinds are matched in order with errors.Is, so for an error joining several
+// sentinels the first one listed decides. Client errors come first: they
+// tell the caller what to fix.
+var kinds = []kind{
+	{models.ErrInvalidRequest, http.StatusBadRequest, "invalid-request", "Invalid request"},
+	{models.ErrInvalidPassword, http.StatusBadRequest, "invalid-password", "Invalid password"},
+	{models.ErrInvalidRole, http.StatusBadRequest, "invalid-role", "Invalid role"},
+	{models.ErrInvalidSort, http.StatusBadRequest, "invalid-sort", "Invalid sort"},
+	{models.ErrInvalidCursor, http.StatusBadRequest, "invalid-cursor", "Invalid cursor"},
+	{models.ErrInvalidTaskStatus, http.StatusBadRequest, "invalid-task-status", "Invalid task status"},
+	{models.ErrInvalidTag, http.StatusBadRequest, "invalid-tag", "Invalid tag"},
+	{models.ErrInvalidRate, http.StatusBadRequest, "invalid-rate", "Invalid hourly rate"},
+	{models.ErrInvalidTaxRate, http.StatusBadRequest, "invalid-tax-rate", "Invalid tax rate"},
+	{models.ErrInvalidTimeInterval, http.StatusBadRequest, "invalid-time-interval", "Invalid time interval"},
+	{models.ErrInvalidTimeZone, http.StatusBadRequest, "invalid-time-zone", "Invalid time zone"},
+	{models.ErrInvalidGroupBy, http.StatusBadRequest, "invalid-group-by", "Invalid grouping"},
+	{models.ErrInvalidFormat, http.StatusBadRequest, "invalid-format", "Invalid format"},
+
+	{models.ErrInvalidCredentials, http.StatusUnauthorized, "invalid-credentials", "Invalid credentials"},
+	{models.ErrInvalidToken, http.StatusUnauthorized, "invalid-token", "Invalid token"},
+	{models.ErrAccessDenied, http.StatusForbidden, "access-denied", "Access denied"},
+
+	{models.ErrClientNotFound, http.StatusNotFound, "client-not-found", "Client not found"},
+	{models.ErrProjectNotFound, http.StatusNotFound, "project-not-found", "Project not found"},
+	{models.ErrUserNotFound, http.StatusNotFound, "user-not-found", "User not found"},
+	{models.ErrTeamNotFound, http.StatusNotFound, "team-not-found", "Team not found"},
+	{models.ErrTagNotFound, http.StatusNotFound, "tag-not-found", "Tag not found"},
+	{models.ErrInvoiceNotFound, http.StatusNotFound, "invoice-not-found", "Invoice not found"},
+	{models.ErrTaskNotFound, http.StatusNotFound, "task-not-found", "Task not found"},
+	{models.ErrTimeEntryNotFound, http.StatusNotFound, "time-entry-not-found", "Time entry not found"},
+
+	{models.ErrUserExists, http.StatusConflict, "user-exists", "User already exists"},
+	{models.ErrTaskClosed, http.StatusConflict, "task-closed", "Task closed"},
+	{models.ErrNothingToInvoice, http.StatusConflict, "nothing-to-invoice", "Nothing to invoice"},
+	{models.ErrInvoiceCurrencies, http.StatusConflict, "invoice-currencies", "Several currencies"},
+	{models.ErrTimerStarted, http.StatusConflict, "timer-started", "Timer already started"},
+	{models.ErrTimerNotStarted, http.StatusConflict, "timer-not-started", "Timer not started"},
+	{models.ErrTimerNotPaused, http.StatusConflict, "timer-not-paused", "Timer not paused"},
+	{models.ErrTimerNotRunning, http.StatusConflict, "timer-not-running", "Timer not running"},
+	{models.ErrTimeEntryOverlap, http.StatusConflict, "time-entry-overlap", "Time entry overlaps"},
+	{models.ErrTimeEntryInvoiced, http.StatusConflict, "time-entry-invoiced", "Time entry invoiced"},
+
+	{models.ErrPeopleInfoResponse, http.StatusBadGateway, "people-info-unavailable", "People info server failed"},
+	{models.ErrMigrationsBehind, http.StatusServiceUnavailable, "migrations-behind", "Database migrations pending"},
+
+	{models.ErrCreateUserResponse, http.StatusInternalServerError, "create-user-failed", "Failed to create user"},
+	{models.ErrGetUserResponse, http.StatusInternalServerError, "get-user-failed", "Failed to get user"},
+	{models.ErrChangeUserInfoResponse, http.StatusInternalServerError, "change-user-failed", "Failed to change user"},
+	{models.ErrUserDeleteResponse, http.StatusInternalServerError, "delete-user-failed", "Failed to delete user"},
+	{models.ErrIssueToken, http.StatusInternalServerError, "issue-token-failed", "Failed to issue token"},
+	{models.ErrDecryptValue, http.StatusInternalServerError, "decrypt-failed", "Failed to decrypt value"},
+
+	{models.ErrCreateClientResponse, http.StatusInternalServerError, "create-client-failed", "Failed to create client"},
+	{models.ErrGetClientResponse, http.StatusInternalServerError, "get-client-failed", "Failed to get client"},
+	{models.ErrChangeClientInfoResponse, http.StatusInternalServerError, "change-client-failed", "Failed to change client"},
+	{models.ErrClientDeleteResponse, http.StatusInternalServerError, "delete-client-failed", "Failed to delete client"},
+
+	{models.ErrCreateProjectResponse, http.StatusInternalServerError, "create-project-failed", "Failed to create project"},
+	{models.ErrGetProjectResponse, http.StatusInternalServerError, "get-project-failed", "Failed to get project"},
+	{models.ErrChangeProjectInfoResponse, http.StatusInternalServerError, "change-project-failed", "Failed to change project"},
+	{models.ErrProjectDeleteResponse, http.StatusInternalServerError, "delete-project-failed", "Failed to delete project"},
+
+	{models.ErrCreateOrganization, http.StatusInternalServerError, "create-organization-failed", "Failed to create organization"},
+
+	{models.ErrCreateTeamResponse, http.StatusInternalServerError, "create-team-failed", "Failed to create team"},
+	{models.ErrGetTeamResponse, http.StatusInternalServerError, "get-team-failed", "Failed to get team"},
+	{models.ErrChangeTeamInfoResponse, http.StatusInternalServerError, "change-team-failed", "Failed to change team"},
+	{models.ErrTeamDeleteResponse, http.StatusInternalServerError, "delete-team-failed", "Failed to delete team"},
+
+	{models.ErrCreateTaskResponse, http.StatusInternalServerError, "create-task-failed", "Failed to create task"},
+	{models.ErrGetTaskResponse, http.StatusInternalServerError, "get-task-failed", "Failed to get task"},
+	{models.ErrUpdateTask, http.StatusInternalServerError, "update-task-failed", "Failed to update task"},
+	{models.ErrTagTask, http.StatusInternalServerError, "tag-task-failed", "Failed to tag task"},
+	{models.ErrCreateInvoice, http.StatusInternalServerError, "create-invoice-failed", "Failed to create invoice"},
+	{models.ErrGetInvoice, http.StatusInternalServerError, "get-invoice-failed", "Failed to get invoice"},
+	{models.ErrCheckTimerStatus, http.StatusInternalServerError, "check-timer-failed", "Failed to check timer status"},
+	{models.ErrStartTimer, http.StatusInternalServerError, "start-timer-failed", "Failed to start timer"},
+	{models.ErrStopTimer, http.StatusInternalServerError, "stop-timer-failed", "Failed to stop timer"},
+	{models.ErrPauseTimer, http.StatusInternalServerError, "pause-timer-failed", "Failed to pause timer"},
+	{models.ErrResumeTimer, http.StatusInternalServerError, "resume-timer-failed", "Failed to resume timer"},
+	{models.ErrGetSegments, http.StatusInternalServerError, "get-segments-failed", "Failed to get timer segments"},
+
+	{models.ErrCreateTimeEntry, http.StatusInternalServerError, "create-time-entry-failed", "Failed to create time entry"},
+	{models.ErrGetTimeEntry, http.StatusInternalServerError, "get-time-entry-failed", "Failed to get time entry"},
+	{models.ErrChangeTimeEntry, http.StatusInternalServerError, "change-time-entry-failed", "Failed to change time entry"},
+	{models.ErrDeleteTimeEntry, http.StatusInternalServerError, "delete-time-entry-failed", "Failed to delete time entry"},
+
+	{models.ErrGetLaborReport, http.StatusInternalServerError, "get-labor-report-failed", "Failed to get labor report"},
+	{models.ErrGetTimesheet, http.StatusInternalServerError, "get-timesheet-failed", "Failed to get timesheet"},
+	{models.ErrBuildInfo, http.StatusInternalServerError, "build-info-unavailable", "Build information unavailable"},
+
+	// Startup errors never reach a handler, they are listed so every
+	// sentinel has a type.
+	{models.ErrLoadEnvFailed, http.StatusInternalServerError, "load-env-failed", "Failed to load environment"},
+	{models.ErrConnectionDBFailed, http.StatusInternalServerError, "database-unavailable", "Failed to connect to database"},
+	{models.ErrServerFailed, http.StatusInternalServerError, "server-failed", "Server failed"},
+	{models.ErrShutdownFailed, http.StatusInternalServerError, "shutdown-failed", "Failed to shut down"},
+	{models.ErrClientFailed, http.StatusInternalServerError, "client-failed", "Failed to create client"},
+	{models.ErrInvalidEncryptionKey, http.StatusInternalServerError, "invalid-encryption-key", "Invalid encryption key"},
+	{models.ErrInvalidTraceExporter, http.StatusInternalServerError, "invalid-trace-exporter", "Invalid trace exporter"},
+}
+
+var internal = kind{
+	status: http.StatusInternalServerError,
+	slug:   "internal",
+	title:  "Internal server error",
+}
+
+// New classifies err. The detail of client errors is the error message; for
+// server errors only the matched sentinel's message is shown, so driver and
+// query errors wrapped in it don't leak.
+func New(r *http.Request, err error) Problem {
+	k := internal
+	for _, v := range kinds {
+		if errors.Is(err, v.err) {
+			k = v
+			break
+		}
+	}
+
+	p := Problem{
+		Type:      TypePrefix + k.slug,
+		Title:     k.title,
+		Status:    k.status,
+		Instance:  r.URL.Path,
+		RequestID: logging.RequestID(r.Context()),
+	}
+	switch {
+	case k.err == nil:
+	case k.status < http.StatusInternalServerError:
+		p.Detail = err.Error()
+	default:
+		p.Detail = k.err.Error()
+	}
+
+	if k.err == models.ErrAccessDenied {
+		p.Reason = models.ReasonRoleRequired
+		var accessErr *models.AccessError
+		if errors.As(err, &accessErr) {
+			p.Reason = accessErr.Reason
+			p.Detail = accessErr.Detail
+		}
+	}
+	return p
+}
+
+// Write writes err as a problem document with the status it maps to.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, New(r, err))
+}
+
+// BadRequest writes a 400 invalid-request problem, detail says what is wrong
+// with the request.
+func BadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	p := New(r, models.ErrInvalidRequest)
+	p.Detail = detail
+	writeProblem(w, p)
+}
+
+func writeProblem(w http.ResponseWriter, p Problem) {
+	data, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(data)
+}
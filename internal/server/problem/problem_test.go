@@ -0,0 +1,113 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	driverErr := errors.New("pq: connection refused")
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		slug   string
+		detail string
+		reason string
+	}{
+		{"client error", models.ErrUserExists, http.StatusConflict, "user-exists", "user already exists", ""},
+		{"wrapped client error keeps detail", fmt.Errorf("%w: task is done", models.ErrTaskClosed),
+			http.StatusConflict, "task-closed", "task is completed or archived: task is done", ""},
+		{"not found", models.ErrTimeEntryNotFound, http.StatusNotFound, "time-entry-not-found",
+			"time entry not found", ""},
+		{"invoiced entry", models.ErrTimeEntryInvoiced, http.StatusConflict, "time-entry-invoiced",
+			"time entry has been invoiced", ""},
+		{"server error hides driver error", errors.Join(models.ErrGetTimeEntry, driverErr),
+			http.StatusInternalServerError, "get-time-entry-failed", "failed to get time entry", ""},
+		{"client error listed first wins", errors.Join(models.ErrCreateInvoice, models.ErrClientNotFound),
+			http.StatusNotFound, "client-not-found", "failed to create invoice\nclient not found", ""},
+		{"access error", &models.AccessError{Reason: models.ReasonNotOwner, Detail: "task belongs to another user"},
+			http.StatusForbidden, "access-denied", "task belongs to another user", models.ReasonNotOwner},
+		{"bare access denied", models.ErrAccessDenied, http.StatusForbidden, "access-denied", "access denied",
+			models.ReasonRoleRequired},
+		{"unknown error", driverErr, http.StatusInternalServerError, "internal", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/task/entry/set", nil)
+			p := New(r, tt.err)
+			if p.Status != tt.status {
+				t.Errorf("status %d, want %d", p.Status, tt.status)
+			}
+			if p.Type != TypePrefix+tt.slug {
+				t.Errorf("type %q, want %q", p.Type, TypePrefix+tt.slug)
+			}
+			if p.Detail != tt.detail {
+				t.Errorf("detail %q, want %q", p.Detail, tt.detail)
+			}
+			if p.Reason != tt.reason {
+				t.Errorf("reason %q, want %q", p.Reason, tt.reason)
+			}
+			if p.Instance != "/task/entry/set" {
+				t.Errorf("instance %q, want the request path", p.Instance)
+			}
+		})
+	}
+}
+
+// Every sentinel has its own type, otherwise clients can't tell them apart.
+func TestKindsAreUnique(t *testing.T) {
+	errs := map[error]bool{}
+	slugs := map[string]bool{}
+	for _, k := range kinds {
+		if errs[k.err] {
+			t.Errorf("%v is listed twice", k.err)
+		}
+		if slugs[k.slug] {
+			t.Errorf("slug %q is used twice", k.slug)
+		}
+		if k.status < 400 || k.title == "" {
+			t.Errorf("%q has status %d and title %q", k.slug, k.status, k.title)
+		}
+		errs[k.err], slugs[k.slug] = true, true
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		write  func(w http.ResponseWriter, r *http.Request)
+		status int
+		detail string
+	}{
+		{"error", func(w http.ResponseWriter, r *http.Request) { Write(w, r, models.ErrInvalidTag) },
+			http.StatusBadRequest, "invalid tag"},
+		{"bad request", func(w http.ResponseWriter, r *http.Request) { BadRequest(w, r, "Invalid request body") },
+			http.StatusBadRequest, "Invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.write(w, httptest.NewRequest(http.MethodPost, "/task/tag/new", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != ContentType {
+				t.Errorf("content type %q, want %q", got, ContentType)
+			}
+			p := Problem{}
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if p.Status != tt.status || p.Detail != tt.detail {
+				t.Errorf("got status %d and detail %q, want %d and %q", p.Status, p.Detail, tt.status, tt.detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type Project interface {
+	CreateProject(ctx context.Context, project models.Project) (models.Project, error)
+	DeleteProject(ctx context.Context, request models.DeleteProjectRequest) error
+	ChangeProject(ctx context.Context, project models.Project) error
+	GetProjects(ctx context.Context, request models.ProjectFilterRequest) (models.ProjectFilterResponse, error)
+}
+
+type Handler struct {
+	service Project
+	log     *logrus.Logger
+}
+
+func NewHandler(service Project, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/new", rs.new)
+	r.Delete("/delete", rs.del)
+	r.Get("/get", rs.get)
+	r.Patch("/set", rs.change)
+
+	return r
+}
+
+// @Summary Creating a new project
+// @Description Handles request to create a new project and returns the project information in JSON. The hourly rate applies to tasks without a rate of their own. Requires the admin or manager role.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Project true "Project name and client ID"
+// @Success 200 {object} models.Project "Created project"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /project/new [post]
+func (rs *Handler) new(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageProjects(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	p := models.Project{}
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil || p.Name == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	p.OrganizationID = &organizationID
+
+	rs.log.WithContext(r.Context()).Infof("Creating new project")
+	newProject, err := rs.service.CreateProject(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(newProject)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Delete project
+// @Description Handles request to delete a project by ID. Tasks of the project are kept without a project. Requires the admin or manager role.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DeleteProjectRequest true "Project ID"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /project/delete [delete]
+func (rs *Handler) del(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageProjects(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	request := models.DeleteProjectRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	request.OrganizationID = auth.Caller(r.Context()).OrganizationID
+	rs.log.WithContext(r.Context()).Infof("Deleting project: %v", request.ID)
+	err = rs.service.DeleteProject(r.Context(), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
+
+// @Summary Get projects
+// @Description Handles request to get projects by filter.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param fields.id query string false "Project ID"
+// @Param fields.name query string false "Project name"
+// @Param fields.client_id query string false "Client ID"
+// @Param limit query int false "Maximum number of results"
+// @Param offset query int false "Offset from the beginning of results"
+// @Success 200 {object}  models.ProjectFilterResponse "List of projects and total results"
+// @Failure 400
+// @Failure 401
+// @Failure 500
+// @Router /project/get [get]
+func (rs *Handler) get(w http.ResponseWriter, r *http.Request) {
+	filter := models.ProjectFilterRequest{}
+	params := r.URL.Query()
+
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	filter.Fields.OrganizationID = &organizationID
+
+	if idStr := params.Get("fields.id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid UUID for fields.id")
+			return
+		}
+		filter.Fields.ID = &id
+	}
+	if name := params.Get("fields.name"); name != "" {
+		filter.Fields.Name = &name
+	}
+	if clientIDStr := params.Get("fields.client_id"); clientIDStr != "" {
+		clientID, err := uuid.Parse(clientIDStr)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid UUID for fields.client_id")
+			return
+		}
+		filter.Fields.ClientID = &clientID
+	}
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.ParseUint(limitStr, 10, 64)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid limit parameter")
+			return
+		}
+		filter.Limit = limit
+	}
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		offset, err := strconv.ParseUint(offsetStr, 10, 64)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid offset parameter")
+			return
+		}
+		filter.Offset = offset
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Getting projects")
+	projects, err := rs.service.GetProjects(r.Context(), filter)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(projects)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Update project
+// @Description Handles request to update project information. A zero hourly rate removes it. Requires the admin or manager role.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Project true "Project Information"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /project/set [patch]
+func (rs *Handler) change(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageProjects(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	p := models.Project{}
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil || p.ID == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	p.OrganizationID = &organizationID
+
+	rs.log.WithContext(r.Context()).Infof("Changing project information")
+	err = rs.service.ChangeProject(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
@@ -0,0 +1,307 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Report interface {
+	GetLaborReport(ctx context.Context, request models.LaborReportRequest) (models.LaborReportResponse, error)
+	GetTeamLaborReport(ctx context.Context, request models.LaborReportRequest) (models.TeamLaborReportResponse, error)
+	WriteTimesheet(ctx context.Context, request models.TimesheetRequest, w io.Writer) error
+}
+
+type Handler struct {
+	service Report
+	log     *logrus.Logger
+}
+
+func NewHandler(service Report, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/labor", rs.labor)
+	r.Get("/team", rs.team)
+	r.Get("/timesheet", rs.timesheet)
+
+	return r
+}
+
+// @Summary Get labor report
+// @Description Handles request to get labor time of a user bucketed by day, ISO week or month in the given time zone, with a breakdown by task within each bucket. Billable tasks with a rate carry the amount earned, buckets and the report the earnings by rate. Viewing other users requires the admin or manager role.
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query string false "User ID, the caller by default"
+// @Param group_by query string false "Bucket size, day by default" Enums(day, week, month)
+// @Param tz query string false "IANA time zone, UTC by default"
+// @Param start_time query string false "Start Time"
+// @Param end_time query string false "End Time"
+// @Success 200 {object} models.LaborReportResponse "Labor time per bucket and task"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /report/labor [get]
+func (rs *Handler) labor(w http.ResponseWriter, r *http.Request) {
+	p := models.LaborReportRequest{}
+	params := r.URL.Query()
+
+	userID := auth.UserID(r.Context())
+	if userIDStr := params.Get("user_id"); userIDStr != "" {
+		var err error
+		userID, err = uuid.Parse(userIDStr)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid UUID for user_id")
+			return
+		}
+	}
+	if err := policy.ViewLaborTime(auth.Caller(r.Context()), userID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	p.UserID = &userID
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	p.OrganizationID = &organizationID
+
+	period, err := parseReportRange(r)
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
+	}
+	p.StartTime, p.EndTime, p.TimeZone = period.StartTime, period.EndTime, period.TimeZone
+	p.GroupBy = params.Get("group_by")
+
+	rs.log.WithContext(r.Context()).Infof("Getting labor report")
+	report, err := rs.service.GetLaborReport(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Get team labor report
+// @Description Handles request to get labor time of every member of a team bucketed by day, ISO week or month in the given time zone, with a breakdown by user within each bucket. Managers see their own team, admins any team of their organization.
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param team_id query string true "Team ID"
+// @Param group_by query string false "Bucket size, day by default" Enums(day, week, month)
+// @Param tz query string false "IANA time zone, UTC by default"
+// @Param start_time query string false "Start Time"
+// @Param end_time query string false "End Time"
+// @Success 200 {object} models.TeamLaborReportResponse "Labor time per bucket and user"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /report/team [get]
+func (rs *Handler) team(w http.ResponseWriter, r *http.Request) {
+	p := models.LaborReportRequest{}
+	params := r.URL.Query()
+
+	if teamIDStr := params.Get("team_id"); teamIDStr != "" {
+		teamID, err := uuid.Parse(teamIDStr)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid UUID for team_id")
+			return
+		}
+		p.TeamID = &teamID
+	} else {
+		problem.BadRequest(w, r, "Missing team_id parameter")
+		return
+	}
+	if err := policy.ViewTeamLabor(auth.Caller(r.Context()), *p.TeamID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	p.OrganizationID = &organizationID
+
+	period, err := parseReportRange(r)
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
+	}
+	p.StartTime, p.EndTime, p.TimeZone = period.StartTime, period.EndTime, period.TimeZone
+	p.GroupBy = params.Get("group_by")
+
+	rs.log.WithContext(r.Context()).Infof("Getting team labor report")
+	report, err := rs.service.GetTeamLaborReport(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Export timesheet
+// @Description Handles request to export one row per finished labor time segment as CSV or XLSX. The format is taken from the format parameter or the Accept header, CSV by default. Exporting other users of the organization requires the admin or manager role.
+// @Tags reports
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param user_id query string false "User ID, the caller by default"
+// @Param all query bool false "Export every user"
+// @Param start_time query string false "Start Time"
+// @Param end_time query string false "End Time"
+// @Param format query string false "File format" Enums(csv, xlsx)
+// @Success 200 {file} file "Timesheet"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /report/timesheet [get]
+func (rs *Handler) timesheet(w http.ResponseWriter, r *http.Request) {
+	p := models.TimesheetRequest{}
+	params := r.URL.Query()
+
+	all, _ := strconv.ParseBool(params.Get("all"))
+	userID := auth.UserID(r.Context())
+	if userIDStr := params.Get("user_id"); userIDStr != "" {
+		var err error
+		userID, err = uuid.Parse(userIDStr)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid UUID for user_id")
+			return
+		}
+	}
+	if all {
+		if err := policy.ViewAllLaborTime(auth.Caller(r.Context())); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+	} else {
+		if err := policy.ViewLaborTime(auth.Caller(r.Context()), userID); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		p.UserID = &userID
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	p.OrganizationID = &organizationID
+
+	period, err := parseReportRange(r)
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
+	}
+	p.StartTime, p.EndTime = period.StartTime, period.EndTime
+
+	p.Format = timesheetFormat(r)
+	contentType, ok := timesheetContentTypes[p.Format]
+	if !ok {
+		problem.BadRequest(w, r, "Invalid format parameter (csv or xlsx expected)")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="timesheet.`+p.Format+`"`)
+
+	rs.log.WithContext(r.Context()).Infof("Exporting timesheet")
+	err = rs.service.WriteTimesheet(r.Context(), p, w)
+	if err != nil {
+		// Once rows have been streamed the status is already sent and the
+		// truncated body is all the client gets.
+		rs.log.WithContext(r.Context()).Error(err)
+		w.Header().Del("Content-Disposition")
+		problem.Write(w, r, err)
+	}
+}
+
+// reportRange is the period a report covers and the time zone it is bucketed
+// in. The time zone is checked by the service.
+type reportRange struct {
+	StartTime *time.Time
+	EndTime   *time.Time
+	TimeZone  string
+}
+
+// parseReportRange reads start_time, end_time and tz shared by the report
+// routes. The error is meant for the client.
+func parseReportRange(r *http.Request) (reportRange, error) {
+	params := r.URL.Query()
+	period := reportRange{TimeZone: params.Get("tz")}
+
+	if startTimeStr := params.Get("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return period, errors.New("Invalid start_time parameter (RFC3339 format expected)")
+		}
+		period.StartTime = &startTime
+	}
+
+	if endTimeStr := params.Get("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return period, errors.New("Invalid end_time parameter (RFC3339 format expected)")
+		}
+		period.EndTime = &endTime
+	}
+
+	return period, nil
+}
+
+var timesheetContentTypes = map[string]string{
+	models.TimesheetCSV:  "text/csv",
+	models.TimesheetXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+func timesheetFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	for format, contentType := range timesheetContentTypes {
+		if strings.Contains(accept, contentType) {
+			return format
+		}
+	}
+	return models.TimesheetCSV
+}
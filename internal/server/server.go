@@ -2,39 +2,134 @@ package server
 
 import (
 	_ "github.com/VikaPaz/time_tracker/docs"
+	"github.com/VikaPaz/time_tracker/internal/logging"
+	"github.com/VikaPaz/time_tracker/internal/metrics"
+	authHandler "github.com/VikaPaz/time_tracker/internal/server/auth"
+	clientHandler "github.com/VikaPaz/time_tracker/internal/server/client"
+	healthHandler "github.com/VikaPaz/time_tracker/internal/server/health"
+	invoiceHandler "github.com/VikaPaz/time_tracker/internal/server/invoice"
+	organizationHandler "github.com/VikaPaz/time_tracker/internal/server/organization"
+	projectHandler "github.com/VikaPaz/time_tracker/internal/server/project"
+	reportHandler "github.com/VikaPaz/time_tracker/internal/server/report"
 	taskHandler "github.com/VikaPaz/time_tracker/internal/server/task"
+	teamHandler "github.com/VikaPaz/time_tracker/internal/server/team"
 	userHandler "github.com/VikaPaz/time_tracker/internal/server/user"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
+	"net/http"
+	"strconv"
+	"time"
 )
 
 type ImplServer struct {
-	user userHandler.User
-	task taskHandler.Task
-	log  *logrus.Logger
+	user    userHandler.User
+	task    taskHandler.Task
+	project projectHandler.Project
+	client  clientHandler.Client
+	report  reportHandler.Report
+	team    teamHandler.Team
+	invoice invoiceHandler.Invoice
+	org     organizationHandler.Organization
+	auth    authHandler.Auth
+	health  healthHandler.Health
+	log     *logrus.Logger
 }
 
-func NewServer(user userHandler.User, task taskHandler.Task, logger *logrus.Logger) *ImplServer {
+func NewServer(user userHandler.User, task taskHandler.Task, project projectHandler.Project,
+	client clientHandler.Client, report reportHandler.Report, team teamHandler.Team, invoice invoiceHandler.Invoice,
+	org organizationHandler.Organization, auth authHandler.Auth, health healthHandler.Health, logger *logrus.Logger) *ImplServer {
 	return &ImplServer{
-		user: user,
-		task: task,
-		log:  logger,
+		user:    user,
+		task:    task,
+		project: project,
+		client:  client,
+		report:  report,
+		team:    team,
+		invoice: invoice,
+		org:     org,
+		auth:    auth,
+		health:  health,
+		log:     logger,
 	}
 }
 
 func (i *ImplServer) Handlers() *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware(i.log))
+	r.Use(metrics.Middleware)
 
+	r.Handle("/metrics", metrics.Handler())
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
 	))
 
 	u := userHandler.NewHandler(i.user, i.log)
 	t := taskHandler.NewHandler(i.task, i.log)
+	p := projectHandler.NewHandler(i.project, i.log)
+	c := clientHandler.NewHandler(i.client, i.log)
+	rp := reportHandler.NewHandler(i.report, i.log)
+	tm := teamHandler.NewHandler(i.team, i.log)
+	in := invoiceHandler.NewHandler(i.invoice, i.log)
+	o := organizationHandler.NewHandler(i.org, i.log)
+	a := authHandler.NewHandler(i.auth, i.log)
+	h := healthHandler.NewHandler(i.health, i.log)
 
-	r.Mount("/user", u.Router())
-	r.Mount("/task", t.Router())
+	r.Mount("/", h.Router())
+	r.Mount("/auth", a.Router())
+
+	r.Group(func(r chi.Router) {
+		// Ahead of auth, so 401 and 403 responses carry the headers too.
+		r.Use(deprecateV1)
+		r.Use(a.Middleware)
+
+		r.Mount("/user", u.Router())
+		r.Mount("/task", t.Router())
+		r.Mount("/project", p.Router())
+		r.Mount("/client", c.Router())
+		r.Mount("/report", rp.Router())
+		r.Mount("/team", tm.Router())
+		r.Mount("/organization", o.Router())
+		r.Mount("/invoices", in.Router())
+	})
+
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(a.Middleware)
+
+		r.Mount("/users", u.RouterV2())
+		r.Mount("/users/{id}/tasks", t.UserRouterV2())
+		r.Mount("/tasks", t.RouterV2())
+	})
 
 	return r
 }
+
+// v1Deprecated is when the verb-shaped routes below were superseded by /v2.
+// They keep working so scripts can migrate gradually.
+var v1Deprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+var deprecatedRoutes = map[string]bool{
+	"/user/new":    true,
+	"/user/get":    true,
+	"/user/set":    true,
+	"/user/delete": true,
+	"/task/new":    true,
+	"/task/get":    true,
+	"/task/start":  true,
+	"/task/stop":   true,
+}
+
+// deprecateV1 marks responses of deprecated routes with the Deprecation
+// header of RFC 9745 and links to the documentation of their replacement.
+func deprecateV1(next http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(v1Deprecated.Unix(), 10)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if deprecatedRoutes[r.URL.Path] {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Link", `</swagger/index.html>; rel="deprecation"; type="text/html"`)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
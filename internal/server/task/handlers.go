@@ -1,12 +1,19 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -16,10 +23,20 @@ type Handler struct {
 }
 
 type Task interface {
-	CreateTask(task models.UserTask) (models.Task, error)
-	StartTask(taskID uuid.UUID) error
-	StopTask(taskID uuid.UUID) error
-	GetTasks(request models.LaborTimeRequest) (models.GetTaskResponse, error)
+	CreateTask(ctx context.Context, task models.UserTask) (models.Task, error)
+	StartTask(ctx context.Context, userID, taskID uuid.UUID) error
+	StopTask(ctx context.Context, userID, taskID uuid.UUID) error
+	GetTasks(ctx context.Context, request models.LaborTimeRequest) (models.GetTaskResponse, error)
+	PauseTask(ctx context.Context, userID, taskID uuid.UUID) error
+	ResumeTask(ctx context.Context, userID, taskID uuid.UUID) error
+	GetSegments(ctx context.Context, userID, taskID uuid.UUID) (models.SegmentsResponse, error)
+	CreateTimeEntry(ctx context.Context, userID uuid.UUID, entry models.TimeEntry) (models.TimeEntry, error)
+	ChangeTimeEntry(ctx context.Context, userID uuid.UUID, entry models.TimeEntry) (models.TimeEntry, error)
+	DeleteTimeEntry(ctx context.Context, userID uuid.UUID, request models.DeleteTimeEntryRequest) error
+	ListTasks(ctx context.Context, filter models.TaskFilter) (models.TaskListResponse, error)
+	UpdateTask(ctx context.Context, userID uuid.UUID, update models.TaskUpdate) (models.Task, error)
+	TagTask(ctx context.Context, userID uuid.UUID, taskTag models.TaskTag) (models.Tag, error)
+	UntagTask(ctx context.Context, userID uuid.UUID, taskTag models.TaskTag) error
 }
 
 func NewHandler(service Task, logger *logrus.Logger) *Handler {
@@ -34,187 +51,697 @@ func (rs *Handler) Router() chi.Router {
 
 	r.Post("/new", rs.new)
 	r.Get("/get", rs.get)
+	r.Get("/list", rs.list)
+	r.Patch("/set", rs.change)
 	r.Patch("/start", rs.start)
 	r.Patch("/stop", rs.stop)
+	r.Patch("/pause", rs.pause)
+	r.Patch("/resume", rs.resume)
+	r.Get("/{id}/segments", rs.segments)
+	r.Post("/entry/new", rs.newEntry)
+	r.Patch("/entry/set", rs.changeEntry)
+	r.Delete("/entry/delete", rs.deleteEntry)
+	r.Post("/tag/new", rs.newTag)
+	r.Delete("/tag/delete", rs.deleteTag)
 
 	return r
 }
 
 // @Summary Create new task
-// @Description Handles request to create a new task for a user.
+// @Description Handles request to create a new task for the caller. Deprecated, use POST /v2/users/{id}/tasks.
 // @Tags tasks
 // @Accept json
 // @Produce json
-// @Param request body models.UserTask true "User ID and text"
+// @Security BearerAuth
+// @Param request body models.UserTask true "Text and project ID"
 // @Success 200 {object} models.Task "Details of the newly created task"
 // @Failure 400
+// @Failure 401
+// @Failure 404
 // @Failure 500
+// @Deprecated
 // @Router /task/new [post]
 func (rs *Handler) new(w http.ResponseWriter, r *http.Request) {
 	t := models.UserTask{}
 	err := json.NewDecoder(r.Body).Decode(&t)
-	if err != nil || t.UserID == nil || t.Text == nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusBadRequest)
+	if err != nil || t.Text == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
 		return
 	}
 
-	rs.log.Infof("Creating new task")
-	newTask, err := rs.service.CreateTask(t)
+	newTask, err := rs.createTask(r, auth.UserID(r.Context()), t)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	data, err := json.Marshal(newTask)
-	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Add("Content-Type", "application/json")
-	_, err = w.Write(data)
-	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
+	rs.writeJSON(w, r, http.StatusOK, newTask)
 }
 
 // @Summary Get tasks
-// @Description Handles request to get tasks for a user based on user ID.
+// @Description Handles request to get labor time of a user's tasks, with the amount earned by billable tasks at the rate applying to them and the earnings by rate. Viewing other users requires the admin or manager role. Deprecated, use GET /v2/users/{id}/tasks.
 // @Tags tasks
 // @Accept json
 // @Produce json
-// @Param user_id query string true "User ID"
+// @Security BearerAuth
+// @Param user_id query string false "User ID, the caller by default"
 // @Param start_time query string false "Start Time"
 // @Param end_time query string false "End Time"
+// @Param project_id query string false "Project ID"
+// @Param client_id query string false "Client ID"
+// @Param tag query string false "Only tasks with this tag"
+// @Param group_by query string false "Group labor time by project, client or tag; a task with several tags counts towards each" Enums(project, client, tag)
 // @Success 200 {array} models.GetTaskResponse "User ID, list of tasks and total"
 // @Failure 400
+// @Failure 401
+// @Failure 403
 // @Failure 500
+// @Deprecated
 // @Router /task/get [get]
 func (rs *Handler) get(w http.ResponseWriter, r *http.Request) {
-	p := models.LaborTimeRequest{}
-
 	// Получаем параметры запроса из URL
 	params := r.URL.Query()
 
-	// Извлекаем и устанавливаем UserID
+	// Извлекаем UserID, по умолчанию берём его из токена
+	userID := auth.UserID(r.Context())
 	if userIDStr := params.Get("user_id"); userIDStr != "" {
-		userID, err := uuid.Parse(userIDStr)
+		var err error
+		userID, err = uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid UUID for user_id", http.StatusBadRequest)
+			problem.BadRequest(w, r, "Invalid UUID for user_id")
 			return
 		}
-		p.UserID = &userID
-	} else {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
-		return
 	}
 
-	// Извлекаем и устанавливаем StartTime
-	if startTimeStr := params.Get("start_time"); startTimeStr != "" {
-		startTime, err := time.Parse(time.RFC3339, startTimeStr)
-		if err != nil {
-			http.Error(w, "Invalid start_time parameter (RFC3339 format expected)", http.StatusBadRequest)
-			return
-		}
-		p.StartTime = &startTime
+	p, err := parseLaborTimeRequest(params)
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
 	}
+	p.UserID = &userID
 
-	// Извлекаем и устанавливаем EndTime
-	if endTimeStr := params.Get("end_time"); endTimeStr != "" {
-		endTime, err := time.Parse(time.RFC3339, endTimeStr)
-		if err != nil {
-			http.Error(w, "Invalid end_time parameter (RFC3339 format expected)", http.StatusBadRequest)
-			return
-		}
-		p.EndTime = &endTime
+	tasks, err := rs.getTasks(r, p)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
 	}
 
-	rs.log.Infof("Getting tasks")
-	tasks, err := rs.service.GetTasks(p)
+	rs.writeJSON(w, r, http.StatusOK, tasks)
+}
+
+// @Summary List tasks
+// @Description Handles request to list a user's tasks with their status, whether or not they have labor time, newest first. Viewing other users requires the admin or manager role.
+// @Tags tasks
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query string false "User ID, the caller by default"
+// @Param status query string false "Task status" Enums(open, done, archived)
+// @Param limit query int false "Maximum number of results"
+// @Param offset query int false "Offset from the beginning of results"
+// @Success 200 {object} models.TaskListResponse "User ID and list of tasks"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /task/list [get]
+func (rs *Handler) list(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseTaskFilter(r.URL.Query(), auth.UserID(r.Context()))
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.BadRequest(w, r, err.Error())
 		return
 	}
 
-	data, err := json.Marshal(tasks)
+	tasks, err := rs.listTasks(r, filter)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	_, err = w.Write(data)
+	rs.writeJSON(w, r, http.StatusOK, tasks)
+}
+
+// @Summary Update task
+// @Description Handles request to rename a task or change its status, hourly rate or billing. A zero rate removes the task rate. Completing or archiving a task stops its timer, reopening it clears completed_at. Users can only update their own tasks.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TaskUpdate true "Task ID, text and status"
+// @Success 200 {object} models.Task "Updated task"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /task/set [patch]
+func (rs *Handler) change(w http.ResponseWriter, r *http.Request) {
+	update := models.TaskUpdate{}
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil || update.ID == uuid.Nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	task, err := rs.updateTask(r, update)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
+		return
 	}
+
+	rs.writeJSON(w, r, http.StatusOK, task)
 }
 
 // @Summary Start timer for task
-// @Description Handles request to start a timer for a task.
+// @Description Handles request to start a timer for a task. Completed or archived tasks can't be started, and neither can a timer that would overlap other labor time of the user. Deprecated, use POST /v2/tasks/{id}/timer:start.
 // @Tags tasks
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param request body models.Timer true "Task ID"
 // @Success 200
 // @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
 // @Failure 500
+// @Deprecated
 // @Router /task/start [patch]
 func (rs *Handler) start(w http.ResponseWriter, r *http.Request) {
 	timer := models.Timer{}
 	err := json.NewDecoder(r.Body).Decode(&timer)
 	if err != nil || timer.TaskID == uuid.Nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusBadRequest)
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
 		return
 	}
 
-	rs.log.Infof("Starting timer")
-	err = rs.service.StartTask(timer.TaskID)
+	err = rs.startTimer(r, timer.TaskID)
 	if err != nil {
-		rs.log.Error(err)
-		if err == models.ErrTimerStarted {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		if err == models.ErrStartTimer {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		return
+		problem.Write(w, r, err)
 	}
 }
 
 // @Summary Stop timer for task
-// @Description Handles request to stop a timer for a task.
+// @Description Handles request to stop a timer for a task. Deprecated, use POST /v2/tasks/{id}/timer:stop.
 // @Tags tasks
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param request body models.Timer true "Task ID"
 // @Success 200
 // @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
 // @Failure 500
+// @Deprecated
 // @Router /task/stop [patch]
 func (rs *Handler) stop(w http.ResponseWriter, r *http.Request) {
 	timer := models.Timer{}
 	err := json.NewDecoder(r.Body).Decode(&timer)
 	if err != nil || timer.TaskID == uuid.Nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusBadRequest)
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	err = rs.stopTimer(r, timer.TaskID)
+	if err != nil {
+		problem.Write(w, r, err)
+	}
+}
+
+// @Summary Pause timer for task
+// @Description Handles request to pause a running timer for a task. The current segment is closed and can be continued with resume.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Timer true "Task ID"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /task/pause [patch]
+func (rs *Handler) pause(w http.ResponseWriter, r *http.Request) {
+	timer := models.Timer{}
+	err := json.NewDecoder(r.Body).Decode(&timer)
+	if err != nil || timer.TaskID == uuid.Nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Pausing timer")
+	err = rs.service.PauseTask(r.Context(), auth.UserID(r.Context()), timer.TaskID)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
+
+// @Summary Resume timer for task
+// @Description Handles request to resume a paused timer for a task. A new segment is started, so the task must still be open and the segment must not overlap other labor time of the user.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Timer true "Task ID"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /task/resume [patch]
+func (rs *Handler) resume(w http.ResponseWriter, r *http.Request) {
+	timer := models.Timer{}
+	err := json.NewDecoder(r.Body).Decode(&timer)
+	if err != nil || timer.TaskID == uuid.Nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Resuming timer")
+	err = rs.service.ResumeTask(r.Context(), auth.UserID(r.Context()), timer.TaskID)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
+
+// @Summary Get timer segments
+// @Description Handles request to get every start/stop interval recorded for a task.
+// @Tags tasks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID"
+// @Success 200 {object} models.SegmentsResponse "Task ID and list of segments"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /task/{id}/segments [get]
+func (rs *Handler) segments(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.BadRequest(w, r, "Invalid UUID for id")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Getting timer segments")
+	segments, err := rs.service.GetSegments(r.Context(), auth.UserID(r.Context()), taskID)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, segments)
+}
+
+// @Summary Create time entry
+// @Description Handles request to add labor time to a task manually with explicit start and stop.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TimeEntry true "Task ID, start and stop"
+// @Success 200 {object} models.TimeEntry "Created time entry"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /task/entry/new [post]
+func (rs *Handler) newEntry(w http.ResponseWriter, r *http.Request) {
+	entry := models.TimeEntry{}
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil || entry.TaskID == nil || entry.Start == nil || entry.Stop == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Creating time entry")
+	newEntry, err := rs.service.CreateTimeEntry(r.Context(), auth.UserID(r.Context()), entry)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeEntry(w, r, newEntry)
+}
+
+// @Summary Update time entry
+// @Description Handles request to change start and/or stop of a time entry. Invoiced entries can't be changed.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TimeEntry true "Time entry ID and new bounds"
+// @Success 200 {object} models.TimeEntry "Updated time entry"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /task/entry/set [patch]
+func (rs *Handler) changeEntry(w http.ResponseWriter, r *http.Request) {
+	entry := models.TimeEntry{}
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil || entry.ID == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Changing time entry")
+	changed, err := rs.service.ChangeTimeEntry(r.Context(), auth.UserID(r.Context()), entry)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeEntry(w, r, changed)
+}
+
+// @Summary Delete time entry
+// @Description Handles request to delete a time entry by ID. Invoiced entries can't be deleted.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DeleteTimeEntryRequest true "Time entry ID"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /task/entry/delete [delete]
+func (rs *Handler) deleteEntry(w http.ResponseWriter, r *http.Request) {
+	request := models.DeleteTimeEntryRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.ID == uuid.Nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Deleting time entry: %v", request.ID)
+	err = rs.service.DeleteTimeEntry(r.Context(), auth.UserID(r.Context()), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
+
+// @Summary Tag task
+// @Description Handles request to attach a tag to a task. The tag is created in the caller's organization on first use, names are case insensitive. Users can only tag their own tasks.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TaskTag true "Task ID and tag"
+// @Success 200 {object} models.Tag "Attached tag"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /task/tag/new [post]
+func (rs *Handler) newTag(w http.ResponseWriter, r *http.Request) {
+	taskTag := models.TaskTag{}
+	err := json.NewDecoder(r.Body).Decode(&taskTag)
+	if err != nil || taskTag.TaskID == uuid.Nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
 		return
 	}
 
-	rs.log.Infof("Stopping timer")
-	err = rs.service.StopTask(timer.TaskID)
+	tag, err := rs.tagTask(r, taskTag)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, tag)
+}
+
+// @Summary Untag task
+// @Description Handles request to detach a tag from a task. Users can only untag their own tasks.
+// @Tags tasks
+// @Accept json
+// @Security BearerAuth
+// @Param request body models.TaskTag true "Task ID and tag"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /task/tag/delete [delete]
+func (rs *Handler) deleteTag(w http.ResponseWriter, r *http.Request) {
+	taskTag := models.TaskTag{}
+	err := json.NewDecoder(r.Body).Decode(&taskTag)
+	if err != nil || taskTag.TaskID == uuid.Nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	err = rs.untagTask(r, taskTag)
+	if err != nil {
+		problem.Write(w, r, err)
+	}
+}
+
+// The helpers below are shared by the v1 and v2 routes.
+
+func (rs *Handler) createTask(r *http.Request, userID uuid.UUID, t models.UserTask) (models.Task, error) {
+	if err := policy.CreateTask(auth.Caller(r.Context()), userID); err != nil {
+		return models.Task{}, err
+	}
+	t.UserID = &userID
+
+	rs.log.WithContext(r.Context()).Infof("Creating new task")
+	newTask, err := rs.service.CreateTask(r.Context(), t)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return models.Task{}, err
+	}
+	return newTask, nil
+}
+
+func (rs *Handler) getTasks(r *http.Request, p models.LaborTimeRequest) (models.GetTaskResponse, error) {
+	caller := auth.Caller(r.Context())
+	if err := policy.ViewLaborTime(caller, *p.UserID); err != nil {
+		return models.GetTaskResponse{}, err
+	}
+	p.OrganizationID = &caller.OrganizationID
+
+	rs.log.WithContext(r.Context()).Infof("Getting tasks")
+	tasks, err := rs.service.GetTasks(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return models.GetTaskResponse{}, err
+	}
+	return tasks, nil
+}
+
+func (rs *Handler) listTasks(r *http.Request, filter models.TaskFilter) (models.TaskListResponse, error) {
+	caller := auth.Caller(r.Context())
+	if err := policy.ViewLaborTime(caller, filter.UserID); err != nil {
+		return models.TaskListResponse{}, err
+	}
+	filter.OrganizationID = caller.OrganizationID
+
+	rs.log.WithContext(r.Context()).Infof("Listing tasks")
+	tasks, err := rs.service.ListTasks(r.Context(), filter)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return models.TaskListResponse{}, err
+	}
+	return tasks, nil
+}
+
+func (rs *Handler) updateTask(r *http.Request, update models.TaskUpdate) (models.Task, error) {
+	rs.log.WithContext(r.Context()).Infof("Updating task: %v", update.ID)
+	task, err := rs.service.UpdateTask(r.Context(), auth.UserID(r.Context()), update)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+func (rs *Handler) tagTask(r *http.Request, taskTag models.TaskTag) (models.Tag, error) {
+	rs.log.WithContext(r.Context()).Infof("Tagging task: %v", taskTag.TaskID)
+	tag, err := rs.service.TagTask(r.Context(), auth.UserID(r.Context()), taskTag)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return models.Tag{}, err
+	}
+	return tag, nil
+}
+
+func (rs *Handler) untagTask(r *http.Request, taskTag models.TaskTag) error {
+	rs.log.WithContext(r.Context()).Infof("Untagging task: %v", taskTag.TaskID)
+	err := rs.service.UntagTask(r.Context(), auth.UserID(r.Context()), taskTag)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return err
+	}
+	return nil
+}
+
+func (rs *Handler) startTimer(r *http.Request, taskID uuid.UUID) error {
+	rs.log.WithContext(r.Context()).Infof("Starting timer")
+	err := rs.service.StartTask(r.Context(), auth.UserID(r.Context()), taskID)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		rs.log.WithContext(r.Context()).Error(err)
+		return err
+	}
+	return nil
+}
+
+func (rs *Handler) stopTimer(r *http.Request, taskID uuid.UUID) error {
+	rs.log.WithContext(r.Context()).Infof("Stopping timer")
+	err := rs.service.StopTask(r.Context(), auth.UserID(r.Context()), taskID)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return err
+	}
+	return nil
+}
+
+// parseLaborTimeRequest reads the filters of a labor time listing, every
+// field but the user.
+func parseLaborTimeRequest(params url.Values) (models.LaborTimeRequest, error) {
+	p := models.LaborTimeRequest{}
+
+	// Извлекаем и устанавливаем StartTime
+	if startTimeStr := params.Get("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return p, errors.New("Invalid start_time parameter (RFC3339 format expected)")
+		}
+		p.StartTime = &startTime
+	}
+
+	// Извлекаем и устанавливаем EndTime
+	if endTimeStr := params.Get("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return p, errors.New("Invalid end_time parameter (RFC3339 format expected)")
+		}
+		p.EndTime = &endTime
+	}
+
+	// Извлекаем и устанавливаем ProjectID
+	if projectIDStr := params.Get("project_id"); projectIDStr != "" {
+		projectID, err := uuid.Parse(projectIDStr)
+		if err != nil {
+			return p, errors.New("Invalid UUID for project_id")
+		}
+		p.ProjectID = &projectID
+	}
+
+	// Извлекаем и устанавливаем ClientID
+	if clientIDStr := params.Get("client_id"); clientIDStr != "" {
+		clientID, err := uuid.Parse(clientIDStr)
+		if err != nil {
+			return p, errors.New("Invalid UUID for client_id")
+		}
+		p.ClientID = &clientID
+	}
+
+	if tagStr := params.Get("tag"); tagStr != "" {
+		tag, err := models.NormalizeTag(tagStr)
+		if err != nil {
+			return p, errors.New("Invalid tag parameter")
+		}
+		p.Tag = &tag
+	}
+
+	// Извлекаем и проверяем группировку
+	switch groupBy := params.Get("group_by"); groupBy {
+	case "", models.GroupByProject, models.GroupByClient, models.GroupByTag:
+		p.GroupBy = groupBy
+	default:
+		return p, errors.New("Invalid group_by parameter (project, client or tag expected)")
+	}
+
+	return p, nil
+}
+
+// parseTaskFilter reads a task listing from query parameters, the user
+// defaults to userID.
+func parseTaskFilter(params url.Values, userID uuid.UUID) (models.TaskFilter, error) {
+	filter := models.TaskFilter{UserID: userID}
+
+	if userIDStr := params.Get("user_id"); userIDStr != "" {
+		id, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return filter, errors.New("Invalid UUID for user_id")
+		}
+		filter.UserID = id
+	}
+	if status := params.Get("status"); status != "" {
+		if !models.ValidTaskStatus(status) {
+			return filter, errors.New("Invalid status parameter (open, done or archived expected)")
+		}
+		filter.Status = &status
+	}
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.ParseUint(limitStr, 10, 64)
+		if err != nil {
+			return filter, errors.New("Invalid limit parameter")
+		}
+		filter.Limit = limit
+	}
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		offset, err := strconv.ParseUint(offsetStr, 10, 64)
+		if err != nil {
+			return filter, errors.New("Invalid offset parameter")
+		}
+		filter.Offset = offset
+	}
+	return filter, nil
+}
+
+func (rs *Handler) writeEntry(w http.ResponseWriter, r *http.Request, entry models.TimeEntry) {
+	rs.writeJSON(w, r, http.StatusOK, entry)
+}
+
+func (rs *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
 		return
 	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
 }
@@ -0,0 +1,314 @@
+package task
+
+import (
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+)
+
+// RouterV2 serves tasks and their timers, the task ID is part of the path.
+func (rs *Handler) RouterV2() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", rs.listV2)
+	r.Patch("/{id}", rs.patch)
+	r.Post("/{id}/timer:start", rs.startByID)
+	r.Post("/{id}/timer:stop", rs.stopByID)
+	r.Put("/{id}/tags/{tag}", rs.putTag)
+	r.Delete("/{id}/tags/{tag}", rs.removeTag)
+
+	return r
+}
+
+// UserRouterV2 serves the tasks of a user. It is mounted below a route with
+// an {id} parameter holding the user ID.
+func (rs *Handler) UserRouterV2() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", rs.listOfUser)
+	r.Post("/", rs.createForUser)
+
+	return r
+}
+
+// @Summary List tasks of a user
+// @Description Handles request to get labor time of a user's tasks, with the amount earned by billable tasks at the rate applying to them and the earnings by rate. Viewing other users requires the admin or manager role.
+// @Tags tasks v2
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param start_time query string false "Start Time"
+// @Param end_time query string false "End Time"
+// @Param project_id query string false "Project ID"
+// @Param client_id query string false "Client ID"
+// @Param tag query string false "Only tasks with this tag"
+// @Param group_by query string false "Group labor time by project, client or tag; a task with several tags counts towards each" Enums(project, client, tag)
+// @Success 200 {object} models.GetTaskResponse "User ID, list of tasks and total"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /v2/users/{id}/tasks [get]
+func (rs *Handler) listOfUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := parseLaborTimeRequest(r.URL.Query())
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
+	}
+	p.UserID = &userID
+
+	tasks, err := rs.getTasks(r, p)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, tasks)
+}
+
+// @Summary Create task for a user
+// @Description Handles request to create a new task. Users can only create tasks for themselves.
+// @Tags tasks v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.UserTask true "Text and project ID"
+// @Success 201 {object} models.Task "Details of the newly created task"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /v2/users/{id}/tasks [post]
+func (rs *Handler) createForUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	t := models.UserTask{}
+	err := json.NewDecoder(r.Body).Decode(&t)
+	if err != nil || t.Text == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	newTask, err := rs.createTask(r, userID, t)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusCreated, newTask)
+}
+
+// @Summary List tasks
+// @Description Handles request to list a user's tasks with their status, whether or not they have labor time, newest first. Viewing other users requires the admin or manager role.
+// @Tags tasks v2
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query string false "User ID, the caller by default"
+// @Param status query string false "Task status" Enums(open, done, archived)
+// @Param limit query int false "Maximum number of results"
+// @Param offset query int false "Offset from the beginning of results"
+// @Success 200 {object} models.TaskListResponse "User ID and list of tasks"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /v2/tasks [get]
+func (rs *Handler) listV2(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseTaskFilter(r.URL.Query(), auth.UserID(r.Context()))
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
+	}
+
+	tasks, err := rs.listTasks(r, filter)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, tasks)
+}
+
+// @Summary Update task
+// @Description Handles request to rename a task or change its status, hourly rate or billing. A zero rate removes the task rate. Completing or archiving a task stops its timer, reopening it clears completed_at. Users can only update their own tasks.
+// @Tags tasks v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID"
+// @Param request body models.TaskUpdate true "Text and status"
+// @Success 200 {object} models.Task "Updated task"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /v2/tasks/{id} [patch]
+func (rs *Handler) patch(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	update := models.TaskUpdate{}
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	update.ID = id
+
+	task, err := rs.updateTask(r, update)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, task)
+}
+
+// @Summary Start timer
+// @Description Handles request to start the timer of a task. Completed or archived tasks can't be started, and neither can a timer that would overlap other labor time of the user.
+// @Tags tasks v2
+// @Security BearerAuth
+// @Param id path string true "Task ID"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /v2/tasks/{id}/timer:start [post]
+func (rs *Handler) startByID(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := rs.startTimer(r, taskID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Stop timer
+// @Description Handles request to stop the timer of a task.
+// @Tags tasks v2
+// @Security BearerAuth
+// @Param id path string true "Task ID"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /v2/tasks/{id}/timer:stop [post]
+func (rs *Handler) stopByID(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := rs.stopTimer(r, taskID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Tag task
+// @Description Handles request to attach a tag to a task, attaching it twice is a no-op. The tag is created in the caller's organization on first use, names are case insensitive. Users can only tag their own tasks.
+// @Tags tasks v2
+// @Security BearerAuth
+// @Param id path string true "Task ID"
+// @Param tag path string true "Tag"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /v2/tasks/{id}/tags/{tag} [put]
+func (rs *Handler) putTag(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := rs.tagTask(r, models.TaskTag{TaskID: taskID, Tag: tagParam(r)})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Untag task
+// @Description Handles request to detach a tag from a task. Users can only untag their own tasks.
+// @Tags tasks v2
+// @Security BearerAuth
+// @Param id path string true "Task ID"
+// @Param tag path string true "Tag"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /v2/tasks/{id}/tags/{tag} [delete]
+func (rs *Handler) removeTag(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := rs.untagTask(r, models.TaskTag{TaskID: taskID, Tag: tagParam(r)})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tagParam returns the {tag} path parameter. chi matches the escaped path
+// when the request needed escaping, so the parameter is unescaped then.
+func tagParam(r *http.Request) string {
+	tag := chi.URLParam(r, "tag")
+	if r.URL.RawPath != "" {
+		if unescaped, err := url.PathUnescape(tag); err == nil {
+			return unescaped
+		}
+	}
+	return tag
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.BadRequest(w, r, "Invalid UUID for id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
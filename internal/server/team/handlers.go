@@ -0,0 +1,241 @@
+package team
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type Team interface {
+	CreateTeam(ctx context.Context, team models.Team) (models.Team, error)
+	DeleteTeam(ctx context.Context, request models.DeleteTeamRequest) error
+	ChangeTeam(ctx context.Context, team models.Team) error
+	GetTeams(ctx context.Context, request models.TeamFilterRequest) (models.TeamFilterResponse, error)
+}
+
+type Handler struct {
+	service Team
+	log     *logrus.Logger
+}
+
+func NewHandler(service Team, logger *logrus.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     logger,
+	}
+}
+
+func (rs *Handler) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/new", rs.new)
+	r.Delete("/delete", rs.del)
+	r.Get("/get", rs.get)
+	r.Patch("/set", rs.change)
+
+	return r
+}
+
+// @Summary Creating a new team
+// @Description Handles request to create a new team and returns the team information in JSON. Requires the admin role.
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Team true "Team name"
+// @Success 200 {object} models.Team "Created team"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /team/new [post]
+func (rs *Handler) new(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageTeams(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	t := models.Team{}
+	err := json.NewDecoder(r.Body).Decode(&t)
+	if err != nil || t.Name == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	t.OrganizationID = &organizationID
+
+	rs.log.WithContext(r.Context()).Infof("Creating new team")
+	newTeam, err := rs.service.CreateTeam(r.Context(), t)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(newTeam)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Delete team
+// @Description Handles request to delete a team by ID. Members of the team are kept without a team. Requires the admin role.
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DeleteTeamRequest true "Team ID"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /team/delete [delete]
+func (rs *Handler) del(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageTeams(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	request := models.DeleteTeamRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	request.OrganizationID = auth.Caller(r.Context()).OrganizationID
+	rs.log.WithContext(r.Context()).Infof("Deleting team: %v", request.ID)
+	err = rs.service.DeleteTeam(r.Context(), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
+
+// @Summary Get teams
+// @Description Handles request to get teams of the caller's organization by filter.
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param fields.id query string false "Team ID"
+// @Param fields.name query string false "Team name"
+// @Param limit query int false "Maximum number of results"
+// @Param offset query int false "Offset from the beginning of results"
+// @Success 200 {object}  models.TeamFilterResponse "List of teams and total results"
+// @Failure 400
+// @Failure 401
+// @Failure 500
+// @Router /team/get [get]
+func (rs *Handler) get(w http.ResponseWriter, r *http.Request) {
+	filter := models.TeamFilterRequest{}
+	params := r.URL.Query()
+
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	filter.Fields.OrganizationID = &organizationID
+
+	if idStr := params.Get("fields.id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid UUID for fields.id")
+			return
+		}
+		filter.Fields.ID = &id
+	}
+	if name := params.Get("fields.name"); name != "" {
+		filter.Fields.Name = &name
+	}
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.ParseUint(limitStr, 10, 64)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid limit parameter")
+			return
+		}
+		filter.Limit = limit
+	}
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		offset, err := strconv.ParseUint(offsetStr, 10, 64)
+		if err != nil {
+			problem.BadRequest(w, r, "Invalid offset parameter")
+			return
+		}
+		filter.Offset = offset
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Getting teams")
+	teams, err := rs.service.GetTeams(r.Context(), filter)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(teams)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// @Summary Update team
+// @Description Handles request to update team information. Requires the admin role.
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.Team true "Team Information"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /team/set [patch]
+func (rs *Handler) change(w http.ResponseWriter, r *http.Request) {
+	if err := policy.ManageTeams(auth.Caller(r.Context())); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	t := models.Team{}
+	err := json.NewDecoder(r.Body).Decode(&t)
+	if err != nil || t.ID == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	organizationID := auth.Caller(r.Context()).OrganizationID
+	t.OrganizationID = &organizationID
+
+	rs.log.WithContext(r.Context()).Infof("Changing team information")
+	err = rs.service.ChangeTeam(r.Context(), t)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
+	}
+}
@@ -3,19 +3,25 @@ package user
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/policy"
+	"github.com/VikaPaz/time_tracker/internal/server/auth"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 type User interface {
-	CreateUser(person models.CreateUserRequest, ctx context.Context) (models.User, error)
-	DeleteUser(request models.DeleteUserRequest) error
-	ChangeUser(user models.User) error
-	GetUsers(request models.FilterRequest) (models.FilterResponse, error)
+	CreateUser(ctx context.Context, person models.CreateUserRequest) (models.User, error)
+	DeleteUser(ctx context.Context, request models.DeleteUserRequest) error
+	ChangeUser(ctx context.Context, user models.User) error
+	GetUsers(ctx context.Context, request models.FilterRequest) (models.FilterResponse, error)
 }
 
 type Handler struct {
@@ -42,201 +48,304 @@ func (rs *Handler) Router() chi.Router {
 }
 
 // @Summary Creating a new user
-// @Description Handles request to create a new user by passportNumber and returns the user information in JSON.
+// @Description Handles request to create a new user by passportNumber and returns the user information in JSON. Requires the admin role. Deprecated, use POST /v2/users.
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param request body models.CreateUserRequest true "Passport"
 // @Success 200 {object} models.User "Created user"
 // @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 409
 // @Failure 500
+// @Deprecated
 // @Router /user/new [post]
 func (rs *Handler) new(w http.ResponseWriter, r *http.Request) {
 	p := models.CreateUserRequest{}
 	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	ctx := r.Context()
-	var newUser models.User
-
-	rs.log.Infof("Creating new user")
-	newUser, err = rs.service.CreateUser(p, ctx)
-	if err != nil {
-		rs.log.Error(err)
-		if err == models.ErrGetUserResponse {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		if err == models.ErrUserExists {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		if err == models.ErrInvalidPassword {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		if err == models.ErrCreateUserResponse {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+	if err != nil || p.PassportNumber == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
 		return
 	}
 
-	data, err := json.Marshal(newUser)
+	newUser, err := rs.createUser(r, p)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	_, err = w.Write(data)
-	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	rs.writeJSON(w, r, http.StatusOK, newUser)
 }
 
 // @Summary Delete user
-// @Description Handles request to delete a user by ID.
+// @Description Handles request to delete a user by ID. Requires the admin role. Deprecated, use DELETE /v2/users/{id}.
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param request body models.DeleteUserRequest true "User ID"
 // @Success 200
 // @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
 // @Failure 500
+// @Deprecated
 // @Router /user/delete [delete]
 func (rs *Handler) del(w http.ResponseWriter, r *http.Request) {
 	request := models.DeleteUserRequest{}
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusBadRequest)
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
 		return
 	}
-	rs.log.Infof("Deleting user: %v", request.ID)
-	err = rs.service.DeleteUser(request)
+
+	err = rs.deleteUser(r, request)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 	}
 }
 
 // @Summary Get users
-// @Description Handles request to get users by filter.
+// @Description Handles request to get users of the caller's organization by filter. Users other than the caller are only visible to admins and managers. Passports are masked unless the caller is an admin. Deprecated, use GET /v2/users.
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param fields.id query string false "User ID"
-// @Param fields.passport query string false "User Passport"
+// @Param fields.team_id query string false "Team ID"
+// @Param fields.passport query string false "User Passport, exact match"
 // @Param fields.name query string false "Username"
 // @Param fields.surname query string false "User Surname"
 // @Param fields.patronymic query string false "User Patronymic"
 // @Param fields.address query string false "User Address"
+// @Param q query string false "Search in surname, name, patronymic and address, typos tolerated; best matches first unless sorted"
 // @Param limit query int false "Maximum number of results"
 // @Param offset query int false "Offset from the beginning of results"
+// @Param sort query string false "Comma separated fields to sort by, - for descending, e.g. surname,-name"
+// @Param cursor query string false "NextCursor of the previous page, can't be combined with offset"
+// @Param include_total query bool false "Count the matching users, true by default"
 // @Success 200 {object}  models.FilterResponse "List of users and total results"
 // @Failure 400
+// @Failure 401
+// @Failure 403
 // @Failure 500
+// @Deprecated
 // @Router /user/get [get]
 func (rs *Handler) get(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query(), "fields.")
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
+	}
+	// v1 always counted the matching users.
+	if !r.URL.Query().Has("include_total") {
+		filter.IncludeTotal = true
+	}
+
+	users, err := rs.getUsers(r, filter)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, users)
+}
+
+// @Summary Update user
+// @Description Handles request to update user information. Users may change their own information; changing others, a role, a team or an hourly rate requires the admin role. A zero hourly rate removes it. Deprecated, use PATCH /v2/users/{id}.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.User true "User Information"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Deprecated
+// @Router /user/set [patch]
+func (rs *Handler) change(w http.ResponseWriter, r *http.Request) {
+	user := models.User{}
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	err = rs.changeUser(r, user)
+	if err != nil {
+		problem.Write(w, r, err)
+	}
+}
+
+// The helpers below are shared by the v1 and v2 routes. They check the
+// caller's permissions, scope the request to the caller's organization and
+// mask passports the caller may not see.
+
+func (rs *Handler) createUser(r *http.Request, p models.CreateUserRequest) (models.User, error) {
+	caller := auth.Caller(r.Context())
+	if err := policy.CreateUser(caller); err != nil {
+		return models.User{}, err
+	}
+	p.OrganizationID = &caller.OrganizationID
+
+	rs.log.WithContext(r.Context()).Infof("Creating new user")
+	newUser, err := rs.service.CreateUser(r.Context(), p)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return models.User{}, err
+	}
+
+	if policy.ViewPassport(caller) != nil {
+		newUser.MaskPassport()
+	}
+	return newUser, nil
+}
+
+func (rs *Handler) deleteUser(r *http.Request, request models.DeleteUserRequest) error {
+	caller := auth.Caller(r.Context())
+	if err := policy.DeleteUser(caller); err != nil {
+		return err
+	}
+	request.OrganizationID = caller.OrganizationID
+
+	rs.log.WithContext(r.Context()).Infof("Deleting user: %v", request.ID)
+	err := rs.service.DeleteUser(r.Context(), request)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return err
+	}
+	return nil
+}
+
+func (rs *Handler) changeUser(r *http.Request, user models.User) error {
+	caller := auth.Caller(r.Context())
+	if err := policy.ChangeUser(caller, user); err != nil {
+		return err
+	}
+	user.OrganizationID = &caller.OrganizationID
+
+	rs.log.WithContext(r.Context()).Infof("Changing user information")
+	err := rs.service.ChangeUser(r.Context(), user)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return err
+	}
+	return nil
+}
+
+func (rs *Handler) getUsers(r *http.Request, filter models.FilterRequest) (models.FilterResponse, error) {
+	caller := auth.Caller(r.Context())
+	filter.Fields.OrganizationID = &caller.OrganizationID
+	if err := policy.ViewUsers(caller, filter); err != nil {
+		return models.FilterResponse{}, err
+	}
+
+	rs.log.WithContext(r.Context()).Infof("Getting users")
+	users, err := rs.service.GetUsers(r.Context(), filter)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		return models.FilterResponse{}, err
+	}
+
+	if policy.ViewPassport(caller) != nil {
+		for i := range users.Users {
+			users.Users[i].MaskPassport()
+		}
+	}
+	return users, nil
+}
+
+// parseFilter reads a user filter from query parameters, prefix is put in
+// front of the user field names: "fields." in v1, nothing in v2.
+func parseFilter(params url.Values, prefix string) (models.FilterRequest, error) {
 	filter := models.FilterRequest{}
-	params := r.URL.Query()
 
-	if idStr := params.Get("fields.id"); idStr != "" {
+	if idStr := params.Get(prefix + "id"); idStr != "" {
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			http.Error(w, "Invalid UUID for fields.id", http.StatusBadRequest)
-			return
+			return filter, errors.New("Invalid UUID for " + prefix + "id")
 		}
 		filter.Fields.ID = &id
 	}
-	if passport := params.Get("fields.passport"); passport != "" {
+	if teamIDStr := params.Get(prefix + "team_id"); teamIDStr != "" {
+		teamID, err := uuid.Parse(teamIDStr)
+		if err != nil {
+			return filter, errors.New("Invalid UUID for " + prefix + "team_id")
+		}
+		filter.Fields.TeamID = &teamID
+	}
+	if passport := params.Get(prefix + "passport"); passport != "" {
 		filter.Fields.Passport = &passport
 	}
-	if name := params.Get("fields.name"); name != "" {
+	if name := params.Get(prefix + "name"); name != "" {
 		filter.Fields.Name = &name
 	}
-	if surname := params.Get("fields.surname"); surname != "" {
+	if surname := params.Get(prefix + "surname"); surname != "" {
 		filter.Fields.Surname = &surname
 	}
-	if patronymic := params.Get("fields.patronymic"); patronymic != "" {
+	if patronymic := params.Get(prefix + "patronymic"); patronymic != "" {
 		filter.Fields.Patronymic = &patronymic
 	}
-	if address := params.Get("fields.address"); address != "" {
+	if address := params.Get(prefix + "address"); address != "" {
 		filter.Fields.Address = &address
 	}
+	filter.Query = strings.TrimSpace(params.Get("q"))
 
 	if limitStr := params.Get("limit"); limitStr != "" {
 		limit, err := strconv.ParseUint(limitStr, 10, 64)
 		if err != nil {
-			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
-			return
+			return filter, errors.New("Invalid limit parameter")
 		}
 		filter.Limit = limit
 	}
 	if offsetStr := params.Get("offset"); offsetStr != "" {
 		offset, err := strconv.ParseUint(offsetStr, 10, 64)
 		if err != nil {
-			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
-			return
+			return filter, errors.New("Invalid offset parameter")
 		}
 		filter.Offset = offset
 	}
 
-	rs.log.Infof("Getting users")
-	users, err := rs.service.GetUsers(filter)
+	sort, err := models.ParseUserSort(params.Get("sort"))
 	if err != nil {
-		rs.log.Error(err)
-		return
+		return filter, err
 	}
-
-	data, err := json.Marshal(users)
-	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	filter.Sort = sort
+	filter.Cursor = params.Get("cursor")
+	if filter.Cursor != "" && filter.Offset != 0 {
+		return filter, errors.New("cursor and offset can't be combined")
 	}
-
-	w.Header().Add("Content-Type", "application/json")
-	_, err = w.Write(data)
-	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+	if includeTotal := params.Get("include_total"); includeTotal != "" {
+		filter.IncludeTotal, err = strconv.ParseBool(includeTotal)
+		if err != nil {
+			return filter, errors.New("Invalid include_total parameter")
+		}
 	}
+	return filter, nil
 }
 
-// @Summary Update user
-// @Description Handles request to update user information
-// @Tags users
-// @Accept json
-// @Produce json
-// @Param request body models.User true "User Information"
-// @Success 200
-// @Failure 400
-// @Failure 500
-// @Router /user/set [patch]
-func (rs *Handler) change(w http.ResponseWriter, r *http.Request) {
-	user := models.User{}
-	err := json.NewDecoder(r.Body).Decode(&user)
+func (rs *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	data, err := json.Marshal(v)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusBadRequest)
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.Write(w, r, err)
 		return
 	}
 
-	rs.log.Infof("Changing user information")
-	err = rs.service.ChangeUser(user)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
 	if err != nil {
-		rs.log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		rs.log.WithContext(r.Context()).Error(err)
 	}
 }
@@ -0,0 +1,205 @@
+package user
+
+import (
+	"encoding/json"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/server/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+)
+
+// RouterV2 serves users as a resource, the user ID is part of the path.
+func (rs *Handler) RouterV2() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", rs.list)
+	r.Post("/", rs.create)
+	r.Get("/{id}", rs.getByID)
+	r.Patch("/{id}", rs.patch)
+	r.Delete("/{id}", rs.remove)
+
+	return r
+}
+
+// @Summary List users
+// @Description Handles request to get users of the caller's organization by filter. Users other than the caller are only visible to admins and managers. Passports are masked unless the caller is an admin.
+// @Tags users v2
+// @Produce json
+// @Security BearerAuth
+// @Param id query string false "User ID"
+// @Param team_id query string false "Team ID"
+// @Param passport query string false "User Passport, exact match"
+// @Param name query string false "Username"
+// @Param surname query string false "User Surname"
+// @Param patronymic query string false "User Patronymic"
+// @Param address query string false "User Address"
+// @Param q query string false "Search in surname, name, patronymic and address, typos tolerated; best matches first unless sorted"
+// @Param limit query int false "Maximum number of results"
+// @Param offset query int false "Offset from the beginning of results"
+// @Param sort query string false "Comma separated fields to sort by, - for descending, e.g. surname,-name"
+// @Param cursor query string false "NextCursor of the previous page, can't be combined with offset"
+// @Param include_total query bool false "Count the matching users"
+// @Success 200 {object} models.FilterResponse "List of users and total results"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /v2/users [get]
+func (rs *Handler) list(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query(), "")
+	if err != nil {
+		problem.BadRequest(w, r, err.Error())
+		return
+	}
+
+	users, err := rs.getUsers(r, filter)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, users)
+}
+
+// @Summary Create user
+// @Description Handles request to create a new user by passportNumber and returns the user information in JSON. Requires the admin role.
+// @Tags users v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateUserRequest true "Passport"
+// @Success 201 {object} models.User "Created user"
+// @Header 201 {string} Location "Path of the created user"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 409
+// @Failure 500
+// @Router /v2/users [post]
+func (rs *Handler) create(w http.ResponseWriter, r *http.Request) {
+	p := models.CreateUserRequest{}
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil || p.PassportNumber == nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	newUser, err := rs.createUser(r, p)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if newUser.ID != nil {
+		w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+newUser.ID.String())
+	}
+	rs.writeJSON(w, r, http.StatusCreated, newUser)
+}
+
+// @Summary Get user
+// @Description Handles request to get a user of the caller's organization. Users other than the caller are only visible to admins and managers.
+// @Tags users v2
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.User "User"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /v2/users/{id} [get]
+func (rs *Handler) getByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := rs.getUsers(r, models.FilterRequest{Fields: models.User{ID: &id}, Limit: 1})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if len(users.Users) == 0 {
+		problem.Write(w, r, models.ErrUserNotFound)
+		return
+	}
+
+	rs.writeJSON(w, r, http.StatusOK, users.Users[0])
+}
+
+// @Summary Update user
+// @Description Handles request to update user information. Users may change their own information; changing others, a role, a team or an hourly rate requires the admin role. A zero hourly rate removes it.
+// @Tags users v2
+// @Accept json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.User true "User Information"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /v2/users/{id} [patch]
+func (rs *Handler) patch(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	user := models.User{}
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		rs.log.WithContext(r.Context()).Error(err)
+		problem.BadRequest(w, r, "Invalid request body")
+		return
+	}
+	user.ID = &id
+
+	err = rs.changeUser(r, user)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Delete user
+// @Description Handles request to delete a user. Requires the admin role.
+// @Tags users v2
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /v2/users/{id} [delete]
+func (rs *Handler) remove(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := rs.deleteUser(r, models.DeleteUserRequest{ID: id})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.BadRequest(w, r, "Invalid UUID for id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+type AuthService struct {
+	repo         Repository
+	users        UserCreator
+	secret       []byte
+	accessTTL    time.Duration
+	refreshTTL   time.Duration
+	organization uuid.UUID
+	log          *logrus.Logger
+}
+
+type Repository interface {
+	GetCredentials(ctx context.Context, passport string) (models.Credentials, error)
+	GetCaller(ctx context.Context, userID uuid.UUID) (models.Caller, error)
+}
+
+type UserCreator interface {
+	CreateUser(ctx context.Context, person models.CreateUserRequest) (models.User, error)
+}
+
+type Config struct {
+	Secret     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// Organization is joined by self-registered users.
+	Organization uuid.UUID
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Type string     `json:"typ"`
+	Role string     `json:"role,omitempty"`
+	Org  uuid.UUID  `json:"org"`
+	Team *uuid.UUID `json:"team,omitempty"`
+}
+
+func NewService(repo Repository, users UserCreator, conf Config, logger *logrus.Logger) *AuthService {
+	return &AuthService{
+		repo:         repo,
+		users:        users,
+		secret:       []byte(conf.Secret),
+		accessTTL:    conf.AccessTTL,
+		refreshTTL:   conf.RefreshTTL,
+		organization: conf.Organization,
+		log:          logger,
+	}
+}
+
+func (a *AuthService) Register(ctx context.Context, person models.CreateUserRequest) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	if person.Password == nil || *person.Password == "" {
+		return models.User{}, models.ErrInvalidPassword
+	}
+	person.OrganizationID = &a.organization
+	person.Role = nil
+	return a.users.CreateUser(ctx, person)
+}
+
+func (a *AuthService) Login(ctx context.Context, request models.LoginRequest) (models.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	a.log.WithContext(ctx).Debugf("Checking credentials")
+	credentials, err := a.repo.GetCredentials(ctx, *request.PassportNumber)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(credentials.PasswordHash), []byte(*request.Password))
+	if err != nil {
+		return models.TokenResponse{}, models.ErrInvalidCredentials
+	}
+
+	a.log.WithContext(ctx).Debugf("Issuing tokens for user: %v", credentials.Caller.ID)
+	return a.issue(credentials.Caller)
+}
+
+func (a *AuthService) Refresh(ctx context.Context, request models.RefreshRequest) (models.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Refresh")
+	defer span.End()
+
+	caller, err := a.parse(request.RefreshToken, models.RefreshToken)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	// The caller is read again so that role and team changes and deleted
+	// users take effect on the next refresh.
+	a.log.WithContext(ctx).Debugf("Refreshing tokens for user: %v", caller.ID)
+	caller, err = a.repo.GetCaller(ctx, caller.ID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return a.issue(caller)
+}
+
+// Authenticate returns the user an access token was issued to.
+func (a *AuthService) Authenticate(token string) (models.Caller, error) {
+	return a.parse(token, models.AccessToken)
+}
+
+func (a *AuthService) issue(caller models.Caller) (models.TokenResponse, error) {
+	now := time.Now()
+
+	access, err := a.sign(caller, models.AccessToken, now, a.accessTTL)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	refresh, err := a.sign(caller, models.RefreshToken, now, a.refreshTTL)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return models.TokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(a.accessTTL.Seconds()),
+	}, nil
+}
+
+func (a *AuthService) sign(caller models.Caller, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   caller.ID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        uuid.NewString(),
+		},
+		Type: tokenType,
+		Role: caller.Role,
+		Org:  caller.OrganizationID,
+		Team: caller.TeamID,
+	})
+
+	signed, err := token.SignedString(a.secret)
+	if err != nil {
+		return "", errors.Join(models.ErrIssueToken, err)
+	}
+	return signed, nil
+}
+
+func (a *AuthService) parse(token string, tokenType string) (models.Caller, error) {
+	c := claims{}
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return models.Caller{}, errors.Join(models.ErrInvalidToken, err)
+	}
+	if c.Type != tokenType {
+		return models.Caller{}, models.ErrInvalidToken
+	}
+
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return models.Caller{}, errors.Join(models.ErrInvalidToken, err)
+	}
+	return models.Caller{ID: userID, Role: c.Role, OrganizationID: c.Org, TeamID: c.Team}, nil
+}
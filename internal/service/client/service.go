@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+type ClientService struct {
+	repo Repository
+	log  *logrus.Logger
+}
+
+type Repository interface {
+	Create(ctx context.Context, client models.Client) (models.Client, error)
+	Get(ctx context.Context, filter models.ClientFilterRequest) (models.ClientFilterResponse, error)
+	Delete(ctx context.Context, request models.DeleteClientRequest) error
+	Set(ctx context.Context, request models.Client) error
+}
+
+func NewService(repo Repository, logger *logrus.Logger) *ClientService {
+	return &ClientService{
+		repo: repo,
+		log:  logger,
+	}
+}
+
+func (c *ClientService) CreateClient(ctx context.Context, client models.Client) (models.Client, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.CreateClient")
+	defer span.End()
+
+	c.log.WithContext(ctx).Debugf("Creating client: %v", *client.Name)
+	result, err := c.repo.Create(ctx, client)
+	if err != nil {
+		return models.Client{}, err
+	}
+	return result, nil
+}
+
+func (c *ClientService) DeleteClient(ctx context.Context, request models.DeleteClientRequest) error {
+	ctx, span := tracing.Start(ctx, "ClientService.DeleteClient")
+	defer span.End()
+
+	c.log.WithContext(ctx).Debugf("Deleting client with ID: %v", request.ID)
+	err := c.repo.Delete(ctx, request)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *ClientService) ChangeClient(ctx context.Context, request models.Client) error {
+	ctx, span := tracing.Start(ctx, "ClientService.ChangeClient")
+	defer span.End()
+
+	c.log.WithContext(ctx).Debugf("Changing client with ID: %v", request.ID)
+	err := c.repo.Set(ctx, request)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *ClientService) GetClients(ctx context.Context, filter models.ClientFilterRequest) (models.ClientFilterResponse, error) {
+	ctx, span := tracing.Start(ctx, "ClientService.GetClients")
+	defer span.End()
+
+	c.log.WithContext(ctx).Debugf("Getting clients with filter: %v", filter)
+	result, err := c.repo.Get(ctx, filter)
+	if err != nil {
+		return models.ClientFilterResponse{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/sirupsen/logrus"
+	"runtime/debug"
+	"time"
+)
+
+// checkTimeout bounds every dependency check so a hanging dependency can't
+// hold up the readiness probe.
+const checkTimeout = 2 * time.Second
+
+type HealthService struct {
+	repo Repository
+	info Prober
+	log  *logrus.Logger
+}
+
+type Repository interface {
+	Ping(ctx context.Context) error
+	Migrations(ctx context.Context) error
+}
+
+type Prober interface {
+	Ping(ctx context.Context) error
+}
+
+// NewService creates the service; info may be nil to skip probing the people
+// info server.
+func NewService(repo Repository, info Prober, logger *logrus.Logger) *HealthService {
+	return &HealthService{
+		repo: repo,
+		info: info,
+		log:  logger,
+	}
+}
+
+func (h *HealthService) Live() models.HealthResponse {
+	return models.HealthResponse{Status: models.StatusUp}
+}
+
+func (h *HealthService) Ready(ctx context.Context) models.HealthResponse {
+	checks := map[string]func(ctx context.Context) error{
+		"postgres":   h.repo.Ping,
+		"migrations": h.repo.Migrations,
+	}
+	if h.info != nil {
+		checks["people_info"] = h.info.Ping
+	}
+
+	response := models.HealthResponse{
+		Status: models.StatusUp,
+		Checks: map[string]models.DependencyStatus{},
+	}
+	for name, check := range checks {
+		status := h.check(ctx, check)
+		if status.Status != models.StatusUp {
+			h.log.WithContext(ctx).Warnf("Readiness check %s failed: %s", name, status.Error)
+			response.Status = models.StatusDown
+		}
+		response.Checks[name] = status
+	}
+	return response
+}
+
+func (h *HealthService) check(ctx context.Context, check func(ctx context.Context) error) models.DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	status := models.DependencyStatus{
+		Status:    models.StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = models.StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
+
+func (h *HealthService) Version() (models.VersionResponse, error) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return models.VersionResponse{}, models.ErrBuildInfo
+	}
+
+	response := models.VersionResponse{
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			response.Revision = setting.Value
+		case "vcs.time":
+			response.BuildTime = setting.Value
+		case "vcs.modified":
+			response.Modified = setting.Value == "true"
+		}
+	}
+	return response, nil
+}
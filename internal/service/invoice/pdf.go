@@ -0,0 +1,71 @@
+package invoice
+
+import (
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/go-pdf/fpdf"
+	"io"
+	"time"
+)
+
+// writePDF lays the invoice out on A4 pages. The built-in fonts only cover
+// Latin-1, other characters are replaced.
+func writePDF(invoice models.Invoice, w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(invoice.Reference(), true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, "Invoice "+invoice.Reference(), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "", 10)
+	rows := [][2]string{
+		{"From", invoice.Seller},
+		{"To", invoice.Client},
+		{"Issued", invoice.IssuedAt.Format(time.DateOnly)},
+		{"Period", invoice.PeriodStart.Format(time.DateOnly) + " - " + invoice.PeriodEnd.Format(time.DateOnly)},
+		{"Currency", invoice.Currency},
+	}
+	for _, row := range rows {
+		pdf.CellFormat(30, 6, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, tr(row[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	widths := []float64{100, 25, 30, 35}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	for i, header := range []string{"Description", "Hours", "Rate", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, header, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range invoice.Lines {
+		pdf.CellFormat(widths[0], 6, tr(line.Description), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, line.Hours.StringFixed(2), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 6, line.UnitPrice.StringFixed(2), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, line.Amount.StringFixed(2), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	totals := [][2]string{
+		{"Subtotal", invoice.Subtotal.StringFixed(2)},
+		{"Tax " + invoice.TaxRate.String() + "%", invoice.Tax.StringFixed(2)},
+		{"Total " + invoice.Currency, invoice.Total.StringFixed(2)},
+	}
+	for i, row := range totals {
+		if i == len(totals)-1 {
+			pdf.SetFont("Helvetica", "B", 11)
+		}
+		pdf.CellFormat(widths[0]+widths[1]+widths[2], 7, row[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 7, row[1], "", 1, "R", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,169 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"io"
+	"regexp"
+	"time"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type InvoiceService struct {
+	repo Repository
+	log  *logrus.Logger
+}
+
+type Repository interface {
+	Create(ctx context.Context, request models.InvoiceRequest,
+		bill func([]models.BillableSegment) (models.Invoice, error)) (models.Invoice, error)
+	Get(ctx context.Context, id, organizationID uuid.UUID) (models.Invoice, error)
+}
+
+func NewService(repo Repository, logger *logrus.Logger) *InvoiceService {
+	return &InvoiceService{
+		repo: repo,
+		log:  logger,
+	}
+}
+
+func (i *InvoiceService) CreateInvoice(ctx context.Context, request models.InvoiceRequest) (models.Invoice, error) {
+	ctx, span := tracing.Start(ctx, "InvoiceService.CreateInvoice")
+	defer span.End()
+
+	if request.TaxRate.IsNegative() || request.TaxRate.GreaterThan(decimal.NewFromInt(100)) ||
+		!request.TaxRate.Equal(request.TaxRate.Round(2)) {
+		return models.Invoice{}, models.ErrInvalidTaxRate
+	}
+	if request.Currency != "" && !currencyCode.MatchString(request.Currency) {
+		return models.Invoice{}, fmt.Errorf("%w: currency must be an ISO 4217 code", models.ErrInvalidRequest)
+	}
+	if request.StartTime != nil && request.EndTime != nil && !request.EndTime.After(*request.StartTime) {
+		return models.Invoice{}, models.ErrInvalidTimeInterval
+	}
+
+	i.log.WithContext(ctx).Debugf("Creating invoice for client ID %v", request.ClientID)
+	invoice, err := i.repo.Create(ctx, request, func(segments []models.BillableSegment) (models.Invoice, error) {
+		return bill(segments, request.TaxRate)
+	})
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	return invoice, nil
+}
+
+// bill turns segments into invoice lines, one per task and rate. The total
+// time of a line is rounded to hundredths of an hour and its amount is the
+// rounded quantity times the rate, so quantity × price always equals the line
+// amount as EN 16931 requires. A line that rounds to no time is left out and
+// its segments stay unbilled. The tax is computed from the subtotal.
+func bill(segments []models.BillableSegment, taxRate decimal.Decimal) (models.Invoice, error) {
+	if len(segments) == 0 {
+		return models.Invoice{}, models.ErrNothingToInvoice
+	}
+
+	type lineKey struct {
+		taskID uuid.UUID
+		rate   string
+	}
+	var keys []lineKey
+	lines := map[lineKey][]*models.BillableSegment{}
+	totals := map[lineKey]time.Duration{}
+
+	invoice := models.Invoice{
+		Currency: segments[0].Rate.Currency,
+		TaxRate:  taxRate,
+		Lines:    []models.InvoiceLine{},
+	}
+	for idx := range segments {
+		s := &segments[idx]
+		if s.Rate.Currency != invoice.Currency {
+			return models.Invoice{}, fmt.Errorf("%w: pick one of %s and %s", models.ErrInvoiceCurrencies,
+				invoice.Currency, s.Rate.Currency)
+		}
+
+		key := lineKey{taskID: s.TaskID, rate: s.Rate.Amount.String()}
+		if _, ok := lines[key]; !ok {
+			keys = append(keys, key)
+		}
+		lines[key] = append(lines[key], s)
+		totals[key] += s.Stop.Sub(s.Start)
+	}
+
+	for _, key := range keys {
+		billed, total := lines[key], totals[key]
+		hours := decimal.NewFromInt(int64(total / time.Second)).Div(decimal.NewFromInt(3600)).Round(2)
+		if hours.IsZero() {
+			continue
+		}
+
+		s := billed[0]
+		taskID := s.TaskID
+		line := models.InvoiceLine{
+			TaskID:      &taskID,
+			Description: s.Task,
+			Hours:       hours,
+			UnitPrice:   s.Rate.Amount,
+			Amount:      s.Rate.Amount.Mul(hours).Round(2),
+		}
+		for _, b := range billed {
+			line.Segments = append(line.Segments, b.ID)
+			if invoice.PeriodStart.IsZero() || b.Start.Before(invoice.PeriodStart) {
+				invoice.PeriodStart = b.Start
+			}
+			if b.Stop.After(invoice.PeriodEnd) {
+				invoice.PeriodEnd = b.Stop
+			}
+		}
+		invoice.Lines = append(invoice.Lines, line)
+		invoice.Subtotal = invoice.Subtotal.Add(line.Amount)
+	}
+	if len(invoice.Lines) == 0 {
+		return models.Invoice{}, models.ErrNothingToInvoice
+	}
+	invoice.Tax = invoice.Subtotal.Mul(taxRate).Div(decimal.NewFromInt(100)).Round(2)
+	invoice.Total = invoice.Subtotal.Add(invoice.Tax)
+	return invoice, nil
+}
+
+func (i *InvoiceService) GetInvoice(ctx context.Context, id, organizationID uuid.UUID) (models.Invoice, error) {
+	ctx, span := tracing.Start(ctx, "InvoiceService.GetInvoice")
+	defer span.End()
+
+	i.log.WithContext(ctx).Debugf("Getting invoice with ID %v", id)
+	invoice, err := i.repo.Get(ctx, id, organizationID)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	return invoice, nil
+}
+
+// WriteInvoice renders the invoice to w in request.Format.
+func (i *InvoiceService) WriteInvoice(ctx context.Context, request models.InvoiceExportRequest, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "InvoiceService.WriteInvoice")
+	defer span.End()
+
+	invoice, err := i.GetInvoice(ctx, request.ID, request.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	i.log.WithContext(ctx).Debugf("Writing %s invoice with ID %v", request.Format, request.ID)
+	switch request.Format {
+	case models.InvoiceJSON:
+		return json.NewEncoder(w).Encode(invoice)
+	case models.InvoicePDF:
+		return writePDF(invoice, w)
+	case models.InvoiceUBL:
+		return writeUBL(invoice, w)
+	default:
+		return models.ErrInvalidFormat
+	}
+}
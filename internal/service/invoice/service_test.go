@@ -0,0 +1,254 @@
+package invoice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"slices"
+	"testing"
+	"time"
+)
+
+var (
+	taskA = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	taskB = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	day   = time.Date(2026, time.October, 5, 9, 0, 0, 0, time.UTC)
+)
+
+func segment(taskID uuid.UUID, start time.Time, d time.Duration, amount, currency string) models.BillableSegment {
+	return models.BillableSegment{
+		ID:     uuid.New(),
+		TaskID: taskID,
+		Task:   "task " + taskID.String()[35:],
+		Start:  start,
+		Stop:   start.Add(d),
+		Rate:   models.Rate{Amount: decimal.RequireFromString(amount), Currency: currency},
+	}
+}
+
+type wantLine struct {
+	task   uuid.UUID
+	hours  string
+	price  string
+	amount string
+}
+
+func TestBill(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []models.BillableSegment
+		taxRate  string
+		unbilled []int
+		err      error
+		lines    []wantLine
+		subtotal string
+		tax      string
+		total    string
+	}{
+		{
+			name:    "nothing to invoice",
+			taxRate: "20",
+			err:     models.ErrNothingToInvoice,
+		},
+		{
+			name: "several currencies",
+			segments: []models.BillableSegment{
+				segment(taskA, day, time.Hour, "80", "EUR"),
+				segment(taskB, day.Add(time.Hour), time.Hour, "80", "USD"),
+			},
+			taxRate: "20",
+			err:     models.ErrInvoiceCurrencies,
+		},
+		{
+			name: "one line per task and rate in order of appearance",
+			segments: []models.BillableSegment{
+				segment(taskB, day, time.Hour, "80", "EUR"),
+				segment(taskA, day.Add(time.Hour), 30*time.Minute, "100", "EUR"),
+				segment(taskB, day.Add(2*time.Hour), 30*time.Minute, "80.00", "EUR"),
+				segment(taskB, day.Add(3*time.Hour), time.Hour, "90", "EUR"),
+			},
+			taxRate: "20",
+			lines: []wantLine{
+				{taskB, "1.5", "80", "120"},
+				{taskA, "0.5", "100", "50"},
+				{taskB, "1", "90", "90"},
+			},
+			subtotal: "260",
+			tax:      "52",
+			total:    "312",
+		},
+		{
+			// 20 minutes are 0.333… hours, billed as 0.33 hours, not 33.33.
+			name: "amount follows the rounded quantity",
+			segments: []models.BillableSegment{
+				segment(taskA, day, 20*time.Minute, "100", "EUR"),
+			},
+			taxRate:  "0",
+			lines:    []wantLine{{taskA, "0.33", "100", "33"}},
+			subtotal: "33",
+			tax:      "0",
+			total:    "33",
+		},
+		{
+			name: "segments of a line are summed before rounding",
+			segments: []models.BillableSegment{
+				segment(taskA, day, 10*time.Minute, "60", "EUR"),
+				segment(taskA, day.Add(time.Hour), 10*time.Minute, "60", "EUR"),
+				segment(taskA, day.Add(2*time.Hour), 10*time.Minute, "60", "EUR"),
+			},
+			taxRate:  "0",
+			lines:    []wantLine{{taskA, "0.5", "60", "30"}},
+			subtotal: "30",
+			tax:      "0",
+			total:    "30",
+		},
+		{
+			// 17 seconds round to 0.00 hours, so the segment stays unbilled
+			// instead of being frozen on the invoice for nothing.
+			name: "lines that round to no time are left out",
+			segments: []models.BillableSegment{
+				segment(taskA, day, time.Hour, "80", "EUR"),
+				segment(taskB, day.Add(time.Hour), 17*time.Second, "80", "EUR"),
+			},
+			taxRate:  "0",
+			unbilled: []int{1},
+			lines:    []wantLine{{taskA, "1", "80", "80"}},
+			subtotal: "80",
+			tax:      "0",
+			total:    "80",
+		},
+		{
+			name: "nothing left after rounding",
+			segments: []models.BillableSegment{
+				segment(taskA, day, 17*time.Second, "80", "EUR"),
+			},
+			taxRate: "20",
+			err:     models.ErrNothingToInvoice,
+		},
+		{
+			name: "tax is rounded once from the subtotal",
+			segments: []models.BillableSegment{
+				segment(taskA, day, time.Hour, "33.33", "EUR"),
+				segment(taskB, day.Add(time.Hour), time.Hour, "33.33", "EUR"),
+			},
+			taxRate: "7.5",
+			lines: []wantLine{
+				{taskA, "1", "33.33", "33.33"},
+				{taskB, "1", "33.33", "33.33"},
+			},
+			subtotal: "66.66",
+			tax:      "5",
+			total:    "71.66",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice, err := bill(tt.segments, decimal.RequireFromString(tt.taxRate))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(invoice.Lines) != len(tt.lines) {
+				t.Fatalf("got %d lines, want %d: %+v", len(invoice.Lines), len(tt.lines), invoice.Lines)
+			}
+			for i, want := range tt.lines {
+				line := invoice.Lines[i]
+				if *line.TaskID != want.task || !line.Hours.Equal(decimal.RequireFromString(want.hours)) ||
+					!line.UnitPrice.Equal(decimal.RequireFromString(want.price)) ||
+					!line.Amount.Equal(decimal.RequireFromString(want.amount)) {
+					t.Errorf("line %d: got task %v, %v h at %v = %v, want %+v", i, *line.TaskID, line.Hours,
+						line.UnitPrice, line.Amount, want)
+				}
+			}
+			totals := []struct {
+				name string
+				got  decimal.Decimal
+				want string
+			}{
+				{"subtotal", invoice.Subtotal, tt.subtotal},
+				{"tax", invoice.Tax, tt.tax},
+				{"total", invoice.Total, tt.total},
+			}
+			for _, v := range totals {
+				if !v.got.Equal(decimal.RequireFromString(v.want)) {
+					t.Errorf("got %s %v, want %v", v.name, v.got, v.want)
+				}
+			}
+			if invoice.Currency != "EUR" {
+				t.Errorf("got currency %q, want EUR", invoice.Currency)
+			}
+
+			var billed []models.BillableSegment
+			for i, s := range tt.segments {
+				if !slices.Contains(tt.unbilled, i) {
+					billed = append(billed, s)
+				}
+			}
+			marked := map[uuid.UUID]bool{}
+			for _, line := range invoice.Lines {
+				for _, id := range line.Segments {
+					marked[id] = true
+				}
+			}
+			if len(marked) != len(billed) {
+				t.Errorf("got %d billed segments, want %d", len(marked), len(billed))
+			}
+			for _, s := range billed {
+				if !marked[s.ID] {
+					t.Errorf("segment %v is not billed", s.ID)
+				}
+			}
+			first, last := billed[0], billed[len(billed)-1]
+			if !invoice.PeriodStart.Equal(first.Start) || !invoice.PeriodEnd.Equal(last.Stop) {
+				t.Errorf("got period %v to %v, want %v to %v", invoice.PeriodStart, invoice.PeriodEnd,
+					first.Start, last.Stop)
+			}
+		})
+	}
+}
+
+// EN 16931 requires the quantity times the price of a UBL line to equal its
+// amount, which rounding the two separately breaks for most durations.
+func TestUBLLinesAgree(t *testing.T) {
+	var segments []models.BillableSegment
+	for i := 1; i <= 240; i++ {
+		segments = append(segments, segment(uuid.New(), day.Add(time.Duration(i)*time.Hour),
+			time.Duration(i)*37*time.Second, "87.45", "EUR"))
+	}
+	invoice, err := bill(segments, decimal.NewFromInt(20))
+	if err != nil {
+		t.Fatalf("bill: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err = writeUBL(invoice, &buf); err != nil {
+		t.Fatalf("writeUBL: %v", err)
+	}
+	doc := struct {
+		Lines []struct {
+			Quantity string `xml:"InvoicedQuantity"`
+			Amount   string `xml:"LineExtensionAmount"`
+			Price    string `xml:"Price>PriceAmount"`
+		} `xml:"InvoiceLine"`
+	}{}
+	if err = xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decoding UBL: %v", err)
+	}
+
+	if len(doc.Lines) != len(segments) {
+		t.Fatalf("got %d lines, want %d", len(doc.Lines), len(segments))
+	}
+	for i, line := range doc.Lines {
+		quantity := decimal.RequireFromString(line.Quantity)
+		price := decimal.RequireFromString(line.Price)
+		if !quantity.Mul(price).Round(2).Equal(decimal.RequireFromString(line.Amount)) {
+			t.Errorf("line %d: %s × %s != %s", i+1, line.Quantity, line.Price, line.Amount)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package invoice
+
+import (
+	"encoding/xml"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/shopspring/decimal"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ublInvoice is the subset of a UBL 2.1 Invoice accounting software needs to
+// import the invoice. Elements must keep the order of the schema.
+type ublInvoice struct {
+	XMLName              xml.Name         `xml:"Invoice"`
+	Xmlns                string           `xml:"xmlns,attr"`
+	XmlnsCac             string           `xml:"xmlns:cac,attr"`
+	XmlnsCbc             string           `xml:"xmlns:cbc,attr"`
+	UBLVersionID         string           `xml:"cbc:UBLVersionID"`
+	ID                   string           `xml:"cbc:ID"`
+	IssueDate            string           `xml:"cbc:IssueDate"`
+	InvoiceTypeCode      string           `xml:"cbc:InvoiceTypeCode"`
+	DocumentCurrencyCode string           `xml:"cbc:DocumentCurrencyCode"`
+	InvoicePeriod        ublPeriod        `xml:"cac:InvoicePeriod"`
+	Supplier             ublParty         `xml:"cac:AccountingSupplierParty"`
+	Customer             ublParty         `xml:"cac:AccountingCustomerParty"`
+	TaxTotal             ublTaxTotal      `xml:"cac:TaxTotal"`
+	MonetaryTotal        ublMonetaryTotal `xml:"cac:LegalMonetaryTotal"`
+	Lines                []ublLine        `xml:"cac:InvoiceLine"`
+}
+
+type ublPeriod struct {
+	StartDate string `xml:"cbc:StartDate"`
+	EndDate   string `xml:"cbc:EndDate"`
+}
+
+type ublParty struct {
+	Name string `xml:"cac:Party>cac:PartyName>cbc:Name"`
+}
+
+type ublAmount struct {
+	Currency string `xml:"currencyID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount   ublAmount      `xml:"cbc:TaxAmount"`
+	TaxSubtotal ublTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount ublAmount `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmount `xml:"cbc:TaxAmount"`
+	CategoryID    string    `xml:"cac:TaxCategory>cbc:ID"`
+	Percent       string    `xml:"cac:TaxCategory>cbc:Percent"`
+	TaxScheme     string    `xml:"cac:TaxCategory>cac:TaxScheme>cbc:ID"`
+}
+
+type ublMonetaryTotal struct {
+	LineExtensionAmount ublAmount `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount  ublAmount `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount  ublAmount `xml:"cbc:TaxInclusiveAmount"`
+	PayableAmount       ublAmount `xml:"cbc:PayableAmount"`
+}
+
+type ublQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublLine struct {
+	ID                  string      `xml:"cbc:ID"`
+	InvoicedQuantity    ublQuantity `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount ublAmount   `xml:"cbc:LineExtensionAmount"`
+	ItemName            string      `xml:"cac:Item>cbc:Name"`
+	PriceAmount         ublAmount   `xml:"cac:Price>cbc:PriceAmount"`
+}
+
+func writeUBL(invoice models.Invoice, w io.Writer) error {
+	amount := func(d decimal.Decimal) ublAmount {
+		return ublAmount{Currency: invoice.Currency, Value: d.StringFixed(2)}
+	}
+
+	// S is the standard rate, Z zero rated goods and services.
+	category := "S"
+	if invoice.TaxRate.IsZero() {
+		category = "Z"
+	}
+
+	doc := ublInvoice{
+		Xmlns:                "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2",
+		XmlnsCac:             "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2",
+		XmlnsCbc:             "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2",
+		UBLVersionID:         "2.1",
+		ID:                   invoice.Reference(),
+		IssueDate:            invoice.IssuedAt.Format(time.DateOnly),
+		InvoiceTypeCode:      "380",
+		DocumentCurrencyCode: invoice.Currency,
+		InvoicePeriod: ublPeriod{
+			StartDate: invoice.PeriodStart.Format(time.DateOnly),
+			EndDate:   invoice.PeriodEnd.Format(time.DateOnly),
+		},
+		Supplier: ublParty{Name: invoice.Seller},
+		Customer: ublParty{Name: invoice.Client},
+		TaxTotal: ublTaxTotal{
+			TaxAmount: amount(invoice.Tax),
+			TaxSubtotal: ublTaxSubtotal{
+				TaxableAmount: amount(invoice.Subtotal),
+				TaxAmount:     amount(invoice.Tax),
+				CategoryID:    category,
+				Percent:       invoice.TaxRate.StringFixed(2),
+				TaxScheme:     "VAT",
+			},
+		},
+		MonetaryTotal: ublMonetaryTotal{
+			LineExtensionAmount: amount(invoice.Subtotal),
+			TaxExclusiveAmount:  amount(invoice.Subtotal),
+			TaxInclusiveAmount:  amount(invoice.Total),
+			PayableAmount:       amount(invoice.Total),
+		},
+	}
+	for i, line := range invoice.Lines {
+		doc.Lines = append(doc.Lines, ublLine{
+			ID: strconv.Itoa(i + 1),
+			// HUR is the UN/ECE code of an hour.
+			InvoicedQuantity:    ublQuantity{UnitCode: "HUR", Value: line.Hours.StringFixed(2)},
+			LineExtensionAmount: amount(line.Amount),
+			ItemName:            line.Description,
+			PriceAmount:         amount(line.UnitPrice),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
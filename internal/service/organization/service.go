@@ -0,0 +1,75 @@
+package organization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+type OrganizationService struct {
+	repo  Repository
+	users UserCreator
+	log   *logrus.Logger
+}
+
+type Repository interface {
+	Create(ctx context.Context, organization models.Organization) (models.Organization, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type UserCreator interface {
+	CreateUser(ctx context.Context, person models.CreateUserRequest) (models.User, error)
+}
+
+func NewService(repo Repository, users UserCreator, logger *logrus.Logger) *OrganizationService {
+	return &OrganizationService{
+		repo:  repo,
+		users: users,
+		log:   logger,
+	}
+}
+
+// CreateOrganization creates the organization and its first admin. If the
+// admin can't be created the organization is removed again, so none is left
+// without somebody to manage it.
+func (o *OrganizationService) CreateOrganization(ctx context.Context,
+	request models.CreateOrganizationRequest) (models.CreateOrganizationResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.CreateOrganization")
+	defer span.End()
+
+	if request.Name == nil || strings.TrimSpace(*request.Name) == "" {
+		return models.CreateOrganizationResponse{}, fmt.Errorf("%w: organization name is empty", models.ErrInvalidRequest)
+	}
+	if request.Admin.PassportNumber == nil {
+		return models.CreateOrganizationResponse{}, fmt.Errorf("%w: admin passport is not set", models.ErrInvalidRequest)
+	}
+	if request.Admin.Password == nil || *request.Admin.Password == "" {
+		return models.CreateOrganizationResponse{}, models.ErrInvalidPassword
+	}
+
+	o.log.WithContext(ctx).Debugf("Creating organization")
+	organization, err := o.repo.Create(ctx, models.Organization{Name: request.Name})
+	if err != nil {
+		return models.CreateOrganizationResponse{}, err
+	}
+
+	role := models.RoleAdmin
+	request.Admin.OrganizationID = organization.ID
+	request.Admin.Role = &role
+
+	o.log.WithContext(ctx).Debugf("Creating admin of organization: %v", *organization.ID)
+	admin, err := o.users.CreateUser(ctx, request.Admin)
+	if err != nil {
+		if deleteErr := o.repo.Delete(ctx, *organization.ID); deleteErr != nil {
+			return models.CreateOrganizationResponse{}, errors.Join(err, deleteErr)
+		}
+		return models.CreateOrganizationResponse{}, err
+	}
+
+	return models.CreateOrganizationResponse{Organization: organization, Admin: admin}, nil
+}
@@ -0,0 +1,87 @@
+package project
+
+import (
+	"context"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+type ProjectService struct {
+	repo Repository
+	log  *logrus.Logger
+}
+
+type Repository interface {
+	Create(ctx context.Context, project models.Project) (models.Project, error)
+	Get(ctx context.Context, filter models.ProjectFilterRequest) (models.ProjectFilterResponse, error)
+	Delete(ctx context.Context, request models.DeleteProjectRequest) error
+	Set(ctx context.Context, request models.Project) error
+}
+
+func NewService(repo Repository, logger *logrus.Logger) *ProjectService {
+	return &ProjectService{
+		repo: repo,
+		log:  logger,
+	}
+}
+
+func (p *ProjectService) CreateProject(ctx context.Context, project models.Project) (models.Project, error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.CreateProject")
+	defer span.End()
+
+	if project.HourlyRate != nil {
+		if err := project.HourlyRate.Validate(); err != nil {
+			return models.Project{}, err
+		}
+	}
+
+	p.log.WithContext(ctx).Debugf("Creating project: %v", *project.Name)
+	result, err := p.repo.Create(ctx, project)
+	if err != nil {
+		return models.Project{}, err
+	}
+	return result, nil
+}
+
+func (p *ProjectService) DeleteProject(ctx context.Context, request models.DeleteProjectRequest) error {
+	ctx, span := tracing.Start(ctx, "ProjectService.DeleteProject")
+	defer span.End()
+
+	p.log.WithContext(ctx).Debugf("Deleting project with ID: %v", request.ID)
+	err := p.repo.Delete(ctx, request)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *ProjectService) ChangeProject(ctx context.Context, request models.Project) error {
+	ctx, span := tracing.Start(ctx, "ProjectService.ChangeProject")
+	defer span.End()
+
+	if request.HourlyRate != nil {
+		if err := request.HourlyRate.Validate(); err != nil {
+			return err
+		}
+	}
+
+	p.log.WithContext(ctx).Debugf("Changing project with ID: %v", request.ID)
+	err := p.repo.Set(ctx, request)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *ProjectService) GetProjects(ctx context.Context, filter models.ProjectFilterRequest) (models.ProjectFilterResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.GetProjects")
+	defer span.End()
+
+	p.log.WithContext(ctx).Debugf("Getting projects with filter: %v", filter)
+	result, err := p.repo.Get(ctx, filter)
+	if err != nil {
+		return models.ProjectFilterResponse{}, err
+	}
+	return result, nil
+}
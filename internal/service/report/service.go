@@ -0,0 +1,256 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"strconv"
+	"time"
+)
+
+var timesheetHeader = []string{"user", "task", "start", "stop", "duration_hours"}
+
+type ReportService struct {
+	repo Repository
+	log  *logrus.Logger
+}
+
+type Repository interface {
+	Labor(ctx context.Context, request models.LaborReportRequest) ([]models.LaborReportRow, error)
+	TeamLabor(ctx context.Context, request models.LaborReportRequest) ([]models.TeamLaborReportRow, error)
+	Timesheet(ctx context.Context, request models.TimesheetRequest, fn func(models.TimesheetRow) error) error
+}
+
+func NewService(repo Repository, logger *logrus.Logger) *ReportService {
+	return &ReportService{
+		repo: repo,
+		log:  logger,
+	}
+}
+
+func (r *ReportService) GetLaborReport(ctx context.Context, request models.LaborReportRequest) (models.LaborReportResponse, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetLaborReport")
+	defer span.End()
+
+	err := checkLaborRequest(&request)
+	if err != nil {
+		return models.LaborReportResponse{}, err
+	}
+
+	r.log.WithContext(ctx).Debugf("Getting labor report with user ID: %v", request.UserID)
+	rows, err := r.repo.Labor(ctx, request)
+	if err != nil {
+		return models.LaborReportResponse{}, err
+	}
+
+	response := models.LaborReportResponse{
+		UserID:   *request.UserID,
+		GroupBy:  request.GroupBy,
+		TimeZone: request.TimeZone,
+		Buckets:  []models.LaborBucket{},
+	}
+
+	var total time.Duration
+	var bucketEarnings, allEarnings models.Earnings
+	for i, v := range rows {
+		if i == 0 || !v.Bucket.Equal(rows[i-1].Bucket) {
+			total = 0
+			bucketEarnings = models.Earnings{}
+			response.Buckets = append(response.Buckets, models.LaborBucket{
+				Start: v.Bucket.Format(time.DateOnly),
+				Tasks: []models.LaborBucketTask{},
+			})
+		}
+
+		bucket := &response.Buckets[len(response.Buckets)-1]
+		task := models.LaborBucketTask{
+			ID:        v.TaskID,
+			Task:      v.Task,
+			LaborTime: models.FormatLaborTime(v.LaborTime),
+			Billable:  v.Billable,
+			Rate:      v.Rate,
+			Amount:    models.BillableAmount(v.Billable, v.Rate, v.LaborTime),
+		}
+		bucket.Tasks = append(bucket.Tasks, task)
+		total += v.LaborTime
+		bucket.LaborTime = models.FormatLaborTime(total)
+		if task.Amount != nil {
+			bucketEarnings.Add(*v.Rate, v.LaborTime)
+			allEarnings.Add(*v.Rate, v.LaborTime)
+		}
+		bucket.Earnings = bucketEarnings.List()
+	}
+	response.Earnings = allEarnings.List()
+
+	return response, nil
+}
+
+func (r *ReportService) GetTeamLaborReport(ctx context.Context, request models.LaborReportRequest) (models.TeamLaborReportResponse, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetTeamLaborReport")
+	defer span.End()
+
+	err := checkLaborRequest(&request)
+	if err != nil {
+		return models.TeamLaborReportResponse{}, err
+	}
+
+	r.log.WithContext(ctx).Debugf("Getting labor report with team ID: %v", request.TeamID)
+	rows, err := r.repo.TeamLabor(ctx, request)
+	if err != nil {
+		return models.TeamLaborReportResponse{}, err
+	}
+
+	response := models.TeamLaborReportResponse{
+		TeamID:   *request.TeamID,
+		GroupBy:  request.GroupBy,
+		TimeZone: request.TimeZone,
+		Buckets:  []models.TeamLaborBucket{},
+	}
+
+	var total time.Duration
+	for i, v := range rows {
+		if i == 0 || !v.Bucket.Equal(rows[i-1].Bucket) {
+			total = 0
+			response.Buckets = append(response.Buckets, models.TeamLaborBucket{
+				Start: v.Bucket.Format(time.DateOnly),
+				Users: []models.TeamLaborBucketUser{},
+			})
+		}
+
+		bucket := &response.Buckets[len(response.Buckets)-1]
+		bucket.Users = append(bucket.Users, models.TeamLaborBucketUser{
+			ID:        v.UserID,
+			User:      v.User,
+			LaborTime: models.FormatLaborTime(v.LaborTime),
+		})
+		total += v.LaborTime
+		bucket.LaborTime = models.FormatLaborTime(total)
+	}
+
+	return response, nil
+}
+
+// checkLaborRequest fills in the default grouping and time zone and rejects
+// values Postgres would not understand.
+func checkLaborRequest(request *models.LaborReportRequest) error {
+	if request.GroupBy == "" {
+		request.GroupBy = models.ReportByDay
+	}
+	if request.GroupBy != models.ReportByDay && request.GroupBy != models.ReportByWeek &&
+		request.GroupBy != models.ReportByMonth {
+		return models.ErrInvalidGroupBy
+	}
+
+	if request.TimeZone == "" {
+		request.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(request.TimeZone); err != nil {
+		return models.ErrInvalidTimeZone
+	}
+	return nil
+}
+
+// WriteTimesheet streams one row per labor_time segment to w in request.Format.
+func (r *ReportService) WriteTimesheet(ctx context.Context, request models.TimesheetRequest, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "ReportService.WriteTimesheet")
+	defer span.End()
+
+	r.log.WithContext(ctx).Debugf("Writing %s timesheet with user ID: %v", request.Format, request.UserID)
+	switch request.Format {
+	case models.TimesheetCSV:
+		return r.writeCSV(ctx, request, w)
+	case models.TimesheetXLSX:
+		return r.writeXLSX(ctx, request, w)
+	default:
+		return models.ErrInvalidFormat
+	}
+}
+
+func (r *ReportService) writeCSV(ctx context.Context, request models.TimesheetRequest, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(timesheetHeader)
+	if err != nil {
+		return err
+	}
+
+	err = r.repo.Timesheet(ctx, request, func(row models.TimesheetRow) error {
+		task := ""
+		if row.Task != nil {
+			task = *row.Task
+		}
+		return cw.Write([]string{
+			row.User,
+			task,
+			row.Start.Format(time.RFC3339),
+			row.Stop.Format(time.RFC3339),
+			strconv.FormatFloat(row.Stop.Sub(row.Start).Hours(), 'f', 2, 64),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *ReportService) writeXLSX(ctx context.Context, request models.TimesheetRequest, w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	dateTime, err := f.NewStyle(&excelize.Style{NumFmt: 22})
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(timesheetHeader))
+	for i, v := range timesheetHeader {
+		header[i] = v
+	}
+	err = sw.SetRow("A1", header)
+	if err != nil {
+		return err
+	}
+
+	line := 1
+	err = r.repo.Timesheet(ctx, request, func(row models.TimesheetRow) error {
+		line++
+		cell, err := excelize.CoordinatesToCellName(1, line)
+		if err != nil {
+			return err
+		}
+
+		var task interface{}
+		if row.Task != nil {
+			task = *row.Task
+		}
+		return sw.SetRow(cell, []interface{}{
+			row.User,
+			task,
+			excelize.Cell{StyleID: dateTime, Value: row.Start},
+			excelize.Cell{StyleID: dateTime, Value: row.Stop},
+			row.Stop.Sub(row.Start).Hours(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = sw.Flush(); err != nil {
+		return errors.Join(models.ErrGetTimesheet, err)
+	}
+	if _, err = f.WriteTo(w); err != nil {
+		return errors.Join(models.ErrGetTimesheet, err)
+	}
+	return nil
+}
@@ -1,10 +1,15 @@
 package task
 
 import (
+	"context"
 	"fmt"
+	"github.com/VikaPaz/time_tracker/internal/metrics"
 	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
 )
 
 type TaskService struct {
@@ -13,11 +18,24 @@ type TaskService struct {
 }
 
 type Repository interface {
-	Create(task models.Task) (models.Task, error)
-	Get(request models.LaborTimeRequest) (models.LaborTimeResponse, error)
-	Start(taskID uuid.UUID) error
-	Stop(taskID uuid.UUID) error
-	IsStarted(taskID uuid.UUID) (bool, error)
+	Create(ctx context.Context, task models.Task) (models.Task, error)
+	Get(ctx context.Context, request models.LaborTimeRequest) (models.LaborTimeResponse, error)
+	Start(ctx context.Context, taskID uuid.UUID) error
+	Stop(ctx context.Context, taskID uuid.UUID) error
+	IsStarted(ctx context.Context, taskID uuid.UUID) (bool, error)
+	Pause(ctx context.Context, taskID uuid.UUID) error
+	Resume(ctx context.Context, taskID uuid.UUID) error
+	Segments(ctx context.Context, taskID uuid.UUID) ([]models.Segment, error)
+	CreateEntry(ctx context.Context, entry models.TimeEntry) (models.TimeEntry, error)
+	GetEntry(ctx context.Context, id uuid.UUID) (models.TimeEntry, error)
+	SetEntry(ctx context.Context, entry models.TimeEntry) error
+	DeleteEntry(ctx context.Context, request models.DeleteTimeEntryRequest) error
+	Owner(ctx context.Context, taskID uuid.UUID) (uuid.UUID, error)
+	List(ctx context.Context, filter models.TaskFilter) ([]models.Task, error)
+	GetTask(ctx context.Context, taskID uuid.UUID) (models.Task, error)
+	Update(ctx context.Context, update models.TaskUpdate) (models.Task, error)
+	AttachTag(ctx context.Context, taskTag models.TaskTag) (models.Tag, error)
+	DetachTag(ctx context.Context, taskTag models.TaskTag) error
 }
 
 func NewService(repo Repository, logger *logrus.Logger) *TaskService {
@@ -27,14 +45,18 @@ func NewService(repo Repository, logger *logrus.Logger) *TaskService {
 	}
 }
 
-func (t *TaskService) CreateTask(userTask models.UserTask) (models.Task, error) {
+func (t *TaskService) CreateTask(ctx context.Context, userTask models.UserTask) (models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.CreateTask")
+	defer span.End()
+
 	newTask := models.Task{
-		Task:   *userTask.Text,
-		UserID: *userTask.UserID,
+		Task:      *userTask.Text,
+		UserID:    *userTask.UserID,
+		ProjectID: userTask.ProjectID,
 	}
 
-	t.log.Debugf("Creating task for user: %s", *userTask.UserID)
-	result, err := t.repo.Create(newTask)
+	t.log.WithContext(ctx).Debugf("Creating task for user: %s", *userTask.UserID)
+	result, err := t.repo.Create(ctx, newTask)
 	if err != nil {
 		return models.Task{}, err
 	}
@@ -42,36 +64,245 @@ func (t *TaskService) CreateTask(userTask models.UserTask) (models.Task, error)
 	return result, nil
 }
 
-func (t *TaskService) StartTask(taskID uuid.UUID) error {
-	t.log.Debugf("Checking status timer with task ID %v", taskID)
-	isStarted, err := t.repo.IsStarted(taskID)
+func (t *TaskService) StartTask(ctx context.Context, userID, taskID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TaskService.StartTask")
+	defer span.End()
+
+	err := t.checkOpen(ctx, userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	t.log.WithContext(ctx).Debugf("Checking status timer with task ID %v", taskID)
+	isStarted, err := t.repo.IsStarted(ctx, taskID)
 	if err != nil {
 		return err
 	}
 	if isStarted {
-		return nil
+		return models.ErrTimerStarted
+	}
+
+	t.log.WithContext(ctx).Debugf("Starting timer with task ID %s", taskID)
+	err = t.repo.Start(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	metrics.TimerEvents.WithLabelValues(metrics.TimerStarted).Inc()
+	return nil
+}
+
+func (t *TaskService) StopTask(ctx context.Context, userID, taskID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TaskService.StopTask")
+	defer span.End()
+
+	err := t.checkOwner(ctx, userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	t.log.WithContext(ctx).Debugf("Stopping timer with task ID %v", taskID)
+	err = t.repo.Stop(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	metrics.TimerEvents.WithLabelValues(metrics.TimerStopped).Inc()
+	return nil
+}
+
+func (t *TaskService) PauseTask(ctx context.Context, userID, taskID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TaskService.PauseTask")
+	defer span.End()
+
+	err := t.checkOwner(ctx, userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	t.log.WithContext(ctx).Debugf("Pausing timer with task ID %v", taskID)
+	err = t.repo.Pause(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	metrics.TimerEvents.WithLabelValues(metrics.TimerPaused).Inc()
+	return nil
+}
+
+func (t *TaskService) ResumeTask(ctx context.Context, userID, taskID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TaskService.ResumeTask")
+	defer span.End()
+
+	err := t.checkOpen(ctx, userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	t.log.WithContext(ctx).Debugf("Resuming timer with task ID %v", taskID)
+	err = t.repo.Resume(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	metrics.TimerEvents.WithLabelValues(metrics.TimerResumed).Inc()
+	return nil
+}
+
+func (t *TaskService) GetSegments(ctx context.Context, userID, taskID uuid.UUID) (models.SegmentsResponse, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetSegments")
+	defer span.End()
+
+	err := t.checkOwner(ctx, userID, taskID)
+	if err != nil {
+		return models.SegmentsResponse{}, err
+	}
+
+	t.log.WithContext(ctx).Debugf("Getting segments with task ID %v", taskID)
+	segments, err := t.repo.Segments(ctx, taskID)
+	if err != nil {
+		return models.SegmentsResponse{}, err
+	}
+
+	response := models.SegmentsResponse{
+		TaskID:   taskID,
+		Segments: []models.Segment{},
+	}
+	now := time.Now()
+	for _, v := range segments {
+		stop := now
+		if v.Stop != nil {
+			stop = *v.Stop
+		}
+		v.LaborTime = models.FormatLaborTime(stop.Sub(v.Start))
+		response.Segments = append(response.Segments, v)
+	}
+
+	return response, nil
+}
+
+func (t *TaskService) CreateTimeEntry(ctx context.Context, userID uuid.UUID, entry models.TimeEntry) (models.TimeEntry, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.CreateTimeEntry")
+	defer span.End()
+
+	err := t.checkOwner(ctx, userID, *entry.TaskID)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+
+	start, stop := entry.Start.UTC(), entry.Stop.UTC()
+	entry.Start, entry.Stop = &start, &stop
+
+	err = checkTimeEntry(entry)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+
+	t.log.WithContext(ctx).Debugf("Creating time entry for task ID %v", *entry.TaskID)
+	result, err := t.repo.CreateEntry(ctx, entry)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	return result, nil
+}
+
+func (t *TaskService) ChangeTimeEntry(ctx context.Context, userID uuid.UUID, request models.TimeEntry) (models.TimeEntry, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.ChangeTimeEntry")
+	defer span.End()
+
+	t.log.WithContext(ctx).Debugf("Getting time entry with ID %v", *request.ID)
+	entry, err := t.repo.GetEntry(ctx, *request.ID)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	err = t.checkOwner(ctx, userID, *entry.TaskID)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+
+	if request.Start != nil {
+		start := request.Start.UTC()
+		entry.Start = &start
+	}
+	if request.Stop != nil {
+		stop := request.Stop.UTC()
+		entry.Stop = &stop
+	}
+
+	err = checkTimeEntry(entry)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+
+	t.log.WithContext(ctx).Debugf("Changing time entry with ID %v", *entry.ID)
+	err = t.repo.SetEntry(ctx, entry)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	return entry, nil
+}
+
+func (t *TaskService) DeleteTimeEntry(ctx context.Context, userID uuid.UUID, request models.DeleteTimeEntryRequest) error {
+	ctx, span := tracing.Start(ctx, "TaskService.DeleteTimeEntry")
+	defer span.End()
+
+	t.log.WithContext(ctx).Debugf("Getting time entry with ID %v", request.ID)
+	entry, err := t.repo.GetEntry(ctx, request.ID)
+	if err != nil {
+		return err
+	}
+	err = t.checkOwner(ctx, userID, *entry.TaskID)
+	if err != nil {
+		return err
+	}
+
+	t.log.WithContext(ctx).Debugf("Deleting time entry with ID %v", request.ID)
+	err = t.repo.DeleteEntry(ctx, request)
+	if err != nil {
+		return err
 	}
+	return nil
+}
 
-	t.log.Debugf("Starting timer with task ID %s", taskID)
-	err = t.repo.Start(taskID)
+// checkOwner makes sure the task belongs to the caller.
+func (t *TaskService) checkOwner(ctx context.Context, userID, taskID uuid.UUID) error {
+	owner, err := t.repo.Owner(ctx, taskID)
 	if err != nil {
 		return err
 	}
+	if owner != userID {
+		return &models.AccessError{Reason: models.ReasonNotOwner, Detail: "task belongs to another user"}
+	}
 	return nil
 }
 
-func (t *TaskService) StopTask(taskID uuid.UUID) error {
-	t.log.Debugf("Stopping timer with task ID %v", taskID)
-	err := t.repo.Stop(taskID)
+// checkOpen makes sure the task belongs to the caller and is neither
+// completed nor archived, so its timer may run.
+func (t *TaskService) checkOpen(ctx context.Context, userID, taskID uuid.UUID) error {
+	task, err := t.repo.GetTask(ctx, taskID)
 	if err != nil {
 		return err
 	}
+	if task.UserID != userID {
+		return &models.AccessError{Reason: models.ReasonNotOwner, Detail: "task belongs to another user"}
+	}
+	if task.Status != models.TaskOpen {
+		return fmt.Errorf("%w: task is %s", models.ErrTaskClosed, task.Status)
+	}
 	return nil
 }
 
-func (t *TaskService) GetTasks(request models.LaborTimeRequest) (models.GetTaskResponse, error) {
-	t.log.Debugf("Getting tasks with user ID: %v", request.UserID)
-	result, err := t.repo.Get(request)
+// checkTimeEntry rejects entries that stop before they start. Overlaps are
+// checked by the repository in the transaction that writes the entry.
+func checkTimeEntry(entry models.TimeEntry) error {
+	if entry.Stop != nil && !entry.Stop.After(*entry.Start) {
+		return models.ErrInvalidTimeInterval
+	}
+	return nil
+}
+
+func (t *TaskService) GetTasks(ctx context.Context, request models.LaborTimeRequest) (models.GetTaskResponse, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTasks")
+	defer span.End()
+
+	t.log.WithContext(ctx).Debugf("Getting tasks with user ID: %v", request.UserID)
+	result, err := t.repo.Get(ctx, request)
 	if err != nil {
 		return models.GetTaskResponse{}, err
 	}
@@ -80,16 +311,179 @@ func (t *TaskService) GetTasks(request models.LaborTimeRequest) (models.GetTaskR
 		UserID: result.UserID,
 		Tasks:  []models.GetTaskInfo{},
 	}
+	earnings := models.Earnings{}
 	for _, v := range result.Tasks {
 		labor := models.GetTaskInfo{
-			ID:   v.ID,
-			Task: *v.Task,
+			ID:        v.ID,
+			Task:      *v.Task,
+			LaborTime: models.FormatLaborTime(*v.LaborTime),
+			ProjectID: v.ProjectID,
+			Project:   v.Project,
+			ClientID:  v.ClientID,
+			Client:    v.Client,
+			Tags:      v.Tags,
+			Billable:  v.Billable,
+			Rate:      v.Rate,
+			Amount:    models.BillableAmount(v.Billable, v.Rate, *v.LaborTime),
 		}
-		h := *v.LaborTime / 360
-		m := *v.LaborTime/60 - h
-		labor.LaborTime = fmt.Sprintf("hours: %v minutes: %v", h, m)
 		response.Tasks = append(response.Tasks, labor)
+		if labor.Amount != nil {
+			earnings.Add(*v.Rate, *v.LaborTime)
+		}
+	}
+
+	response.Earnings = earnings.List()
+
+	if request.GroupBy != "" {
+		response.Groups = groupLaborTime(result.Tasks, request.GroupBy)
 	}
 
 	return response, nil
 }
+
+func (t *TaskService) ListTasks(ctx context.Context, filter models.TaskFilter) (models.TaskListResponse, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.ListTasks")
+	defer span.End()
+
+	if filter.Status != nil && !models.ValidTaskStatus(*filter.Status) {
+		return models.TaskListResponse{}, models.ErrInvalidTaskStatus
+	}
+
+	t.log.WithContext(ctx).Debugf("Listing tasks with user ID: %v", filter.UserID)
+	tasks, err := t.repo.List(ctx, filter)
+	if err != nil {
+		return models.TaskListResponse{}, err
+	}
+	return models.TaskListResponse{UserID: filter.UserID, Tasks: tasks}, nil
+}
+
+func (t *TaskService) UpdateTask(ctx context.Context, userID uuid.UUID, update models.TaskUpdate) (models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.UpdateTask")
+	defer span.End()
+
+	if update.Status != nil && !models.ValidTaskStatus(*update.Status) {
+		return models.Task{}, models.ErrInvalidTaskStatus
+	}
+	if update.Task != nil && strings.TrimSpace(*update.Task) == "" {
+		return models.Task{}, fmt.Errorf("%w: task text is empty", models.ErrInvalidRequest)
+	}
+	if update.HourlyRate != nil {
+		if err := update.HourlyRate.Validate(); err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	err := t.checkOwner(ctx, userID, update.ID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	t.log.WithContext(ctx).Debugf("Updating task with ID %v", update.ID)
+	task, err := t.repo.Update(ctx, update)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+func (t *TaskService) TagTask(ctx context.Context, userID uuid.UUID, taskTag models.TaskTag) (models.Tag, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.TagTask")
+	defer span.End()
+
+	var err error
+	taskTag.Tag, err = models.NormalizeTag(taskTag.Tag)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	err = t.checkOwner(ctx, userID, taskTag.TaskID)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	t.log.WithContext(ctx).Debugf("Tagging task with ID %v", taskTag.TaskID)
+	tag, err := t.repo.AttachTag(ctx, taskTag)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	return tag, nil
+}
+
+func (t *TaskService) UntagTask(ctx context.Context, userID uuid.UUID, taskTag models.TaskTag) error {
+	ctx, span := tracing.Start(ctx, "TaskService.UntagTask")
+	defer span.End()
+
+	var err error
+	taskTag.Tag, err = models.NormalizeTag(taskTag.Tag)
+	if err != nil {
+		return err
+	}
+
+	err = t.checkOwner(ctx, userID, taskTag.TaskID)
+	if err != nil {
+		return err
+	}
+
+	t.log.WithContext(ctx).Debugf("Untagging task with ID %v", taskTag.TaskID)
+	err = t.repo.DetachTag(ctx, taskTag)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// groupLaborTime sums labor time of tasks by project, client or tag, keeping
+// the order in which groups first appear.
+func groupLaborTime(tasks []models.TaskInfo, groupBy string) []models.LaborGroup {
+	var order []uuid.UUID
+	names := map[uuid.UUID]*string{}
+	totals := map[uuid.UUID]time.Duration{}
+
+	for _, v := range tasks {
+		for _, g := range groupsOf(v, groupBy) {
+			key := uuid.Nil
+			if g.ID != nil {
+				key = *g.ID
+			}
+			if _, ok := totals[key]; !ok {
+				order = append(order, key)
+				names[key] = g.Name
+			}
+			totals[key] += *v.LaborTime
+		}
+	}
+
+	groups := make([]models.LaborGroup, 0, len(order))
+	for _, key := range order {
+		group := models.LaborGroup{
+			Name:      names[key],
+			LaborTime: models.FormatLaborTime(totals[key]),
+		}
+		if key != uuid.Nil {
+			id := key
+			group.ID = &id
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// groupsOf returns the groups a task counts towards, without labor time.
+func groupsOf(task models.TaskInfo, groupBy string) []models.LaborGroup {
+	switch groupBy {
+	case models.GroupByClient:
+		return []models.LaborGroup{{ID: task.ClientID, Name: task.Client}}
+	case models.GroupByTag:
+		if len(task.Tags) == 0 {
+			return []models.LaborGroup{{}}
+		}
+		groups := make([]models.LaborGroup, 0, len(task.Tags))
+		for _, tag := range task.Tags {
+			id, name := tag.ID, tag.Name
+			groups = append(groups, models.LaborGroup{ID: &id, Name: &name})
+		}
+		return groups
+	default:
+		return []models.LaborGroup{{ID: task.ProjectID, Name: task.Project}}
+	}
+}
@@ -0,0 +1,75 @@
+package team
+
+import (
+	"context"
+	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+type TeamService struct {
+	repo Repository
+	log  *logrus.Logger
+}
+
+type Repository interface {
+	Create(ctx context.Context, team models.Team) (models.Team, error)
+	Get(ctx context.Context, filter models.TeamFilterRequest) (models.TeamFilterResponse, error)
+	Delete(ctx context.Context, request models.DeleteTeamRequest) error
+	Set(ctx context.Context, request models.Team) error
+}
+
+func NewService(repo Repository, logger *logrus.Logger) *TeamService {
+	return &TeamService{
+		repo: repo,
+		log:  logger,
+	}
+}
+
+func (t *TeamService) CreateTeam(ctx context.Context, team models.Team) (models.Team, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.CreateTeam")
+	defer span.End()
+
+	t.log.WithContext(ctx).Debugf("Creating team: %v", *team.Name)
+	result, err := t.repo.Create(ctx, team)
+	if err != nil {
+		return models.Team{}, err
+	}
+	return result, nil
+}
+
+func (t *TeamService) DeleteTeam(ctx context.Context, request models.DeleteTeamRequest) error {
+	ctx, span := tracing.Start(ctx, "TeamService.DeleteTeam")
+	defer span.End()
+
+	t.log.WithContext(ctx).Debugf("Deleting team with ID: %v", request.ID)
+	err := t.repo.Delete(ctx, request)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *TeamService) ChangeTeam(ctx context.Context, request models.Team) error {
+	ctx, span := tracing.Start(ctx, "TeamService.ChangeTeam")
+	defer span.End()
+
+	t.log.WithContext(ctx).Debugf("Changing team with ID: %v", request.ID)
+	err := t.repo.Set(ctx, request)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *TeamService) GetTeams(ctx context.Context, filter models.TeamFilterRequest) (models.TeamFilterResponse, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.GetTeams")
+	defer span.End()
+
+	t.log.WithContext(ctx).Debugf("Getting teams with filter: %v", filter)
+	result, err := t.repo.Get(ctx, filter)
+	if err != nil {
+		return models.TeamFilterResponse{}, err
+	}
+	return result, nil
+}
@@ -2,8 +2,11 @@ package user
 
 import (
 	"context"
+	"fmt"
 	"github.com/VikaPaz/time_tracker/internal/models"
+	"github.com/VikaPaz/time_tracker/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
@@ -17,10 +20,10 @@ type Client interface {
 }
 
 type Repository interface {
-	Create(user models.User) (models.User, error)
-	Get(models.FilterRequest) (models.FilterResponse, error)
-	Delete(request models.DeleteUserRequest) error
-	Set(request models.User) error
+	Create(ctx context.Context, user models.User) (models.User, error)
+	Get(ctx context.Context, filter models.FilterRequest) (models.FilterResponse, error)
+	Delete(ctx context.Context, request models.DeleteUserRequest) error
+	Set(ctx context.Context, request models.User) error
 }
 
 type PeopleInfo interface {
@@ -35,10 +38,22 @@ func NewService(repo Repository, userData Client, logger *logrus.Logger) *UserSe
 	}
 }
 
-func (u *UserService) CreateUser(person models.CreateUserRequest, ctx context.Context) (models.User, error) {
-	u.log.Debugf("Checking user exists")
+func (u *UserService) CreateUser(ctx context.Context, person models.CreateUserRequest) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	if person.OrganizationID == nil {
+		return models.User{}, fmt.Errorf("%w: organization is not set", models.ErrInvalidRequest)
+	}
+	if person.PassportNumber == nil {
+		return models.User{}, fmt.Errorf("%w: passport is not set", models.ErrInvalidRequest)
+	}
+
+	// The unique passport index decides, this only fails early and returns
+	// the existing user.
+	u.log.WithContext(ctx).Debugf("Checking user exists")
 	filter := models.FilterRequest{Fields: models.User{Passport: person.PassportNumber}, Limit: 1}
-	result, err := u.repo.Get(filter)
+	result, err := u.repo.Get(ctx, filter)
 	if err != nil {
 		return models.User{}, err
 	}
@@ -46,14 +61,26 @@ func (u *UserService) CreateUser(person models.CreateUserRequest, ctx context.Co
 		return result.Users[0], models.ErrUserExists
 	}
 
-	u.log.Infof("Getting user information")
+	u.log.WithContext(ctx).Infof("Getting user information")
 	info, err := u.userData.GetInf(ctx, person)
 	if err != nil {
 		return models.User{}, err
 	}
 
-	u.log.Debugf("Creating user: %v", info)
-	userInf, err := u.repo.Create(info)
+	if person.Password != nil {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*person.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return models.User{}, models.ErrCreateUserResponse
+		}
+		passwordHash := string(hash)
+		info.PasswordHash = &passwordHash
+	}
+
+	info.OrganizationID = person.OrganizationID
+	info.Role = person.Role
+
+	u.log.WithContext(ctx).Debugf("Creating user: %v", info)
+	userInf, err := u.repo.Create(ctx, info)
 	if err != nil {
 		return models.User{}, err
 	}
@@ -61,27 +88,46 @@ func (u *UserService) CreateUser(person models.CreateUserRequest, ctx context.Co
 	return userInf, nil
 }
 
-func (u *UserService) DeleteUser(request models.DeleteUserRequest) error {
-	u.log.Debugf("Deleting user with ID: %v", request.ID)
-	err := u.repo.Delete(request)
+func (u *UserService) DeleteUser(ctx context.Context, request models.DeleteUserRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	u.log.WithContext(ctx).Debugf("Deleting user with ID: %v", request.ID)
+	err := u.repo.Delete(ctx, request)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (u *UserService) ChangeUser(request models.User) error {
-	u.log.Debugf("Changing user information: %v", request)
-	err := u.repo.Set(request)
+func (u *UserService) ChangeUser(ctx context.Context, request models.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeUser")
+	defer span.End()
+
+	if request.Role != nil && *request.Role != models.RoleAdmin && *request.Role != models.RoleManager &&
+		*request.Role != models.RoleEmployee {
+		return models.ErrInvalidRole
+	}
+	if request.HourlyRate != nil {
+		if err := request.HourlyRate.Validate(); err != nil {
+			return err
+		}
+	}
+
+	u.log.WithContext(ctx).Debugf("Changing user information: %v", request)
+	err := u.repo.Set(ctx, request)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (u *UserService) GetUsers(filter models.FilterRequest) (models.FilterResponse, error) {
-	u.log.Debugf("Getting users with filter: %v", filter)
-	result, err := u.repo.Get(filter)
+func (u *UserService) GetUsers(ctx context.Context, filter models.FilterRequest) (models.FilterResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUsers")
+	defer span.End()
+
+	u.log.WithContext(ctx).Debugf("Getting users with filter: %v", filter)
+	result, err := u.repo.Get(ctx, filter)
 	if err != nil {
 		return models.FilterResponse{}, err
 	}
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table labor_time
+    add column if not exists paused boolean not null default false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table labor_time
+    drop column paused;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists clients
+(
+    id   uuid default uuid_generate_v4() primary key,
+    name text not null
+    );
+
+create table if not exists projects
+(
+    id        uuid default uuid_generate_v4() primary key,
+    name      text not null,
+    client_id uuid references clients on delete set null
+    );
+
+alter table tasks
+    add column if not exists project_id uuid references projects on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table tasks
+    drop column project_id;
+drop table projects;
+drop table clients;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column if not exists password_hash text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table users
+    drop column password_hash;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists roles
+(
+    name        varchar(32) primary key,
+    description text
+    );
+
+insert into roles (name, description)
+values ('admin', 'Manages users, roles and everybody''s labor time'),
+       ('manager', 'Views labor time of other users and manages projects and clients'),
+       ('employee', 'Tracks own labor time')
+on conflict do nothing;
+
+-- Every existing and newly registered user is an employee; the first admin
+-- has to be promoted directly in the database.
+alter table users
+    add column if not exists role varchar(32) not null default 'employee' references roles;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table users
+    drop column role;
+drop table roles;
+-- +goose StatementEnd
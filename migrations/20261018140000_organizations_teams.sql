@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists organizations
+(
+    id   uuid default uuid_generate_v4() primary key,
+    name text not null
+    );
+
+-- Existing data and self-registered users belong to the default organization.
+insert into organizations (id, name)
+values ('00000000-0000-0000-0000-000000000001', 'Default')
+on conflict do nothing;
+
+create table if not exists teams
+(
+    id              uuid default uuid_generate_v4() primary key,
+    name            text not null,
+    organization_id uuid not null references organizations on delete cascade
+    );
+
+alter table users
+    add column if not exists organization_id uuid not null
+        default '00000000-0000-0000-0000-000000000001' references organizations on delete cascade,
+    add column if not exists team_id uuid references teams on delete set null;
+
+alter table clients
+    add column if not exists organization_id uuid not null
+        default '00000000-0000-0000-0000-000000000001' references organizations on delete cascade;
+alter table clients
+    alter column organization_id drop default;
+
+alter table projects
+    add column if not exists organization_id uuid not null
+        default '00000000-0000-0000-0000-000000000001' references organizations on delete cascade;
+alter table projects
+    alter column organization_id drop default;
+
+create index if not exists users_organization_id_idx on users (organization_id);
+create index if not exists users_team_id_idx on users (team_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table projects
+    drop column organization_id;
+alter table clients
+    drop column organization_id;
+alter table users
+    drop column team_id,
+    drop column organization_id;
+drop table teams;
+drop table organizations;
+-- +goose StatementEnd
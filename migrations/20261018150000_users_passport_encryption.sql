@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Passports are encrypted by the application, so the column has to hold the
+-- ciphertext. Existing plain text values are encrypted and indexed on the next
+-- start of the service.
+alter table users
+    alter column passport type text,
+    add column if not exists passport_index varchar(64);
+
+-- The index is unique, so concurrent registrations with one passport can't
+-- both succeed and logins find exactly one user.
+create unique index if not exists users_passport_index_idx on users (passport_index)
+    where passport_index is not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The key encryption key only exists in the application configuration, so
+-- encrypted passports can't be decrypted here. Rolling back over them would
+-- leave ciphertext nobody can read in a column that is expected to hold plain
+-- text, so the rollback refuses to run until no encrypted passport is left.
+do
+$$
+    begin
+        if exists(select 1 from users where passport like 'v1:%') then
+            raise exception 'users.passport holds encrypted passports, rolling back would make them unreadable';
+        end if;
+    end
+$$;
+
+drop index if exists users_passport_index_idx;
+
+alter table users
+    drop column passport_index,
+    alter column passport type varchar(100);
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Users are listed per organization in ID order by default, or by surname and
+-- name. The expressions match the ORDER BY of the listing, so cursors can seek.
+create index if not exists users_organization_id_id_idx on users (organization_id, id);
+create index if not exists users_organization_id_surname_name_idx
+    on users (organization_id, coalesce(surname, ''), coalesce(name, ''), id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists users_organization_id_surname_name_idx;
+drop index if exists users_organization_id_id_idx;
+-- +goose StatementEnd
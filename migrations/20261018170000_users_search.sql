@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Users are searched by word similarity over their names and address. The
+-- expression has to match searchExpr of the user repository to be used.
+create extension if not exists pg_trgm;
+
+create index if not exists users_search_trgm_idx on users using gin (
+    (coalesce(surname, '') || ' ' || coalesce(name, '') || ' ' ||
+     coalesce(patronymic, '') || ' ' || coalesce(address, '')) gin_trgm_ops
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists users_search_trgm_idx;
+-- +goose StatementEnd
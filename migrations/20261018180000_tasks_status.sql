@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table tasks
+    add column if not exists status       varchar(16) not null default 'open'
+        check (status in ('open', 'done', 'archived')),
+    add column if not exists created_at   timestamp   not null default (now() at time zone 'utc'),
+    add column if not exists completed_at timestamp;
+
+create index if not exists tasks_user_id_created_at_idx on tasks (user_id, created_at desc);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists tasks_user_id_created_at_idx;
+
+alter table tasks
+    drop column completed_at,
+    drop column created_at,
+    drop column status;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists tags
+(
+    id              uuid default uuid_generate_v4() primary key,
+    organization_id uuid        not null references organizations on delete cascade,
+    name            varchar(64) not null,
+    unique (organization_id, name)
+);
+
+create table if not exists task_tags
+(
+    task_id uuid not null references tasks on delete cascade,
+    tag_id  uuid not null references tags on delete cascade,
+    primary key (task_id, tag_id)
+);
+
+create index if not exists task_tags_tag_id_idx on task_tags (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table task_tags;
+drop table tags;
+-- +goose StatementEnd
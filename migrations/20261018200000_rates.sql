@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column if not exists hourly_rate   numeric(12, 2) check (hourly_rate > 0),
+    add column if not exists rate_currency char(3),
+    add constraint users_rate_currency_check check ((hourly_rate is null) = (rate_currency is null));
+
+alter table projects
+    add column if not exists hourly_rate   numeric(12, 2) check (hourly_rate > 0),
+    add column if not exists rate_currency char(3),
+    add constraint projects_rate_currency_check check ((hourly_rate is null) = (rate_currency is null));
+
+alter table tasks
+    add column if not exists hourly_rate   numeric(12, 2) check (hourly_rate > 0),
+    add column if not exists rate_currency char(3),
+    add column if not exists billable      boolean not null default true,
+    add constraint tasks_rate_currency_check check ((hourly_rate is null) = (rate_currency is null));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table tasks
+    drop column billable,
+    drop column rate_currency,
+    drop column hourly_rate;
+
+alter table projects
+    drop column rate_currency,
+    drop column hourly_rate;
+
+alter table users
+    drop column rate_currency,
+    drop column hourly_rate;
+-- +goose StatementEnd
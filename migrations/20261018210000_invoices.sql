@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists invoices
+(
+    id              uuid default uuid_generate_v4() primary key,
+    organization_id uuid           not null references organizations on delete cascade,
+    number          bigint         not null,
+    -- Names are frozen, an issued invoice doesn't change with its parties.
+    seller          text           not null,
+    client_id       uuid references clients on delete set null,
+    client          text           not null,
+    issued_at       timestamp      not null default (now() at time zone 'utc'),
+    period_start    timestamp      not null,
+    period_end      timestamp      not null,
+    currency        char(3)        not null,
+    tax_rate        numeric(5, 2)  not null check (tax_rate >= 0),
+    subtotal        numeric(14, 2) not null,
+    tax             numeric(14, 2) not null,
+    total           numeric(14, 2) not null,
+    created_by      uuid references users on delete set null,
+    unique (organization_id, number)
+);
+
+create table if not exists invoice_lines
+(
+    invoice_id  uuid           not null references invoices on delete cascade,
+    position    int            not null,
+    task_id     uuid references tasks on delete set null,
+    description text           not null,
+    hours       numeric(10, 2) not null,
+    unit_price  numeric(12, 2) not null,
+    amount      numeric(14, 2) not null,
+    primary key (invoice_id, position)
+);
+
+alter table labor_time
+    add column if not exists invoice_id uuid references invoices on delete set null;
+
+create index if not exists labor_time_invoice_id_idx on labor_time (invoice_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table labor_time
+    drop column invoice_id;
+drop table invoice_lines;
+drop table invoices;
+-- +goose StatementEnd